@@ -1,141 +1,394 @@
 package cmd
 
 import (
-	"path/filepath"
+	"strings"
 
+	"github.com/marcelriegr/draide/internal/config"
 	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/failure"
 	"github.com/marcelriegr/draide/pkg/imgtools"
+	"github.com/marcelriegr/draide/pkg/registry"
 	"github.com/marcelriegr/draide/pkg/types"
 	"github.com/marcelriegr/draide/pkg/ui"
 
-	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var buildCmd = &cobra.Command{
-	Use:   "build CONTEXT_DIR",
+	Use:   "build [CONTEXT_DIR]",
 	Short: "Build an image",
-	Long:  `Description tbd`,
-	Args:  cobra.ExactArgs(1),
-	PreRun: func(cmd *cobra.Command, args []string) {
-		viper.BindPFlag("dockerfile", cmd.PersistentFlags().Lookup("dockerfile"))
-		viper.BindPFlag("nocache", cmd.PersistentFlags().Lookup("no-cache"))
-		viper.BindPFlag("labels", cmd.PersistentFlags().Lookup("label"))
-	},
+	Long: `Build the image of CONTEXT_DIR or, if omitted, all images declared in the images section of the configuration file.
+
+With --changed-since or --changed only images whose context directory, Dockerfile or watch paths changed are built,
+together with all images based on them.
+
+Checks declared in the test section of the configuration file are run against the built image. If any check fails,
+draide exits with code 9 and nothing is pushed.
+
+With --dry-run the configuration, templates, tags, credentials and rules are resolved as usual, but instead of building
+the resulting plan is printed. Neither Docker nor any registry is contacted.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		repositoryFormat := viper.GetString("repository-format")
-		templateVars := parser.GenerateTemplateVars(parser.GenerateTemplateVarsOptions{})
-		contextDir, _ := homedir.Expand(args[0])
-		contextDir, err := filepath.Abs(contextDir)
-		if err != nil {
-			ui.Log(err.Error())
-			ui.ErrorAndExit(3, "Failed parsing context directory path")
+		push := viper.GetBool("push")
+		if rule := config.PushRule(); rule != "" {
+			ui.Log("Push %s by rule %s", stringTernary(push, "enabled", "disabled"), rule)
 		}
-		dockerfile := filepath.ToSlash(parser.Template(viper.GetString("dockerfile"), templateVars))
-		noCache := viper.GetBool("nocache")
-
-		push, err := cmd.Flags().GetBool("push")
+		changedSince, err := cmd.Flags().GetString("changed-since")
 		if err != nil {
 			ui.ErrorAndExit(1, err.Error())
 		}
-
-		tagTemplates := viper.GetStringSlice("tags")
-		tags := parser.RepositoryName(repositoryFormat, tagTemplates, templateVars)
-
-		labelTemplates := viper.GetStringMapString("labels")
-		labels := map[string]string{}
-		for k, v := range labelTemplates {
-			labels[k] = parser.Template(v, templateVars)
-		}
-
-		buildArgTemplates, err := cmd.Flags().GetStringToString("build-arg")
+		changed, err := cmd.Flags().GetBool("changed")
 		if err != nil {
 			ui.ErrorAndExit(1, err.Error())
 		}
-		if len(buildArgTemplates) == 0 {
-			var buildArgsFromConfig []types.KeyValueConfig
-
-			// unmarshal values into an interface as a workaround to enable case-sensitive data loading from config file
-			// ref: https://github.com/spf13/viper/issues/373
-			err = viper.UnmarshalKey("buildArgs", &buildArgsFromConfig)
-			if err != nil {
-				ui.Log(err.Error())
-				ui.ErrorAndExit(1, "Failed parsing build arguments from configuration file")
-			}
 
-			for _, v := range buildArgsFromConfig {
-				buildArgTemplates[v.Key] = v.Value
-			}
-		}
-		buildArgs := map[string]string{}
-		for k, v := range buildArgTemplates {
-			buildArgs[k] = parser.Template(v, templateVars)
-		}
-
-		if viper.GetBool("verbose") {
-			ui.Log("Used configuration:")
-			ui.Log("> repository name format: %s", repositoryFormat)
-			ui.Log("> registry: %s", stringTernary(templateVars["REGISTRY"] == "", "<none>", templateVars["REGISTRY"]))
-			ui.Log("> namespace: %s", stringTernary(templateVars["NAMESPACE"] == "", "<none>", templateVars["NAMESPACE"]))
-			ui.Log("> base image name: %s", templateVars["IMAGE_NAME"])
-			ui.Log("> dockerfile: %s", dockerfile)
-			ui.Log("> context: %s", contextDir)
-			ui.Log("> no-cache: %v", noCache)
-			ui.Log("> labels:%s", stringTernary(len(labels) == 0, " <none>", ""))
-			for k, v := range labels {
-				ui.Log("  - %s: %s", k, v)
-			}
-			ui.Log("> build args:%s", stringTernary(len(buildArgs) == 0, " <none>", ""))
-			for k, v := range buildArgs {
-				ui.Log("  - %s: %s", k, v)
-			}
-			ui.Log("> tags:%s", stringTernary(len(tags) == 0, " <none>", ""))
-			for _, v := range tags {
-				ui.Log("  - %s", v)
+		specs := resolveImageSpecs(args)
+		if changed || changedSince != "" {
+			specs = changedImageSpecs(specs, changedSince)
+			if len(specs) == 0 {
+				nothingToDo("build", "Nothing to build. No image inputs changed.")
+				return
 			}
 		}
 
-		ui.Info("Building image...")
-		if len(tags) == 0 {
-			ui.ErrorAndExit(1, "Abort. No valid image tag found.")
+		if isDryRun(cmd) {
+			plan := newPlan("build")
+			for _, spec := range specs {
+				restoreConfig := applyImageConfig(spec)
+				plan.AddImage(planBuild(cmd, spec, push))
+				restoreConfig()
+			}
+			printPlan(cmd, plan)
+			return
 		}
-		imgtools.Build(contextDir, imgtools.BuildOptions{
-			Dockerfile: dockerfile,
-			BuildArgs:  buildArgs,
-			Tags:       tags,
-			Labels:     labels,
-			NoCache:    noCache,
-		})
 
-		for _, repository := range tags {
-			ui.Success(" > %s built succefully", repository)
+		rep := newReport("build")
+		images := make([]*report.Image, len(specs))
+		for i, spec := range specs {
+			checkCancelled()
+			restoreConfig := applyImageConfig(spec)
+			images[i] = buildImage(cmd, spec, push, rep)
+			restoreConfig()
 		}
-
+		// images are pushed once all of them are built and tested, so that a failing image leaves the registry untouched
 		if push {
-			ui.Info("Pushing image...")
-			for _, repository := range tags {
-				imgtools.Push(repository, imgtools.PushOptions{
-					Auth: imgtools.AuthConfig{
-						Username: viper.GetString("username"),
-						Password: viper.GetString("password"),
-					},
-				})
-				ui.Success(" > %s pushed succefully", repository)
+			for i, spec := range specs {
+				checkCancelled()
+				restoreConfig := applyImageConfig(spec)
+				pushBuiltImage(images[i])
+				restoreConfig()
 			}
 		}
-
+		printTimings(rep)
+		writeReport(rep)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(buildCmd)
+	addDryRunFlags(buildCmd)
 
 	buildCmd.PersistentFlags().StringP("dockerfile", "f", "Dockerfile", "Path to Dockerfile relative to CONTEXT_DIR. Value may contain template variable.")
 	buildCmd.PersistentFlags().StringToString("label", map[string]string{}, "Image label. Value may contain template variable.")
 	buildCmd.PersistentFlags().StringToString("build-arg", map[string]string{}, "Build argument. Value may contain template variable.")
 	buildCmd.PersistentFlags().Bool("no-cache", false, "Set build noCache option")
 	buildCmd.PersistentFlags().Bool("push", false, "Push image after building")
+	buildCmd.PersistentFlags().Bool("skip-existing", false, "Skip building and testing if an image with the identity tag already exists in the registry. With --push, the remaining tags are added to the existing image.")
+	buildCmd.PersistentFlags().String("identity-tag", "%COMMIT_HASH%", "Tag identifying the image content, used by --skip-existing. Value may contain template variable.")
+	buildCmd.PersistentFlags().Bool("skip-tests", false, "Skip the checks of the test section")
+	buildCmd.PersistentFlags().String("changed-since", "", "Only build images whose inputs changed since the given git revision")
+	buildCmd.PersistentFlags().Bool("changed", false, "Only build images whose inputs changed since the merge-base with the default branch")
+
+	// flags are bound on initialization so that presets can extend values given on the command line
+	config.BindFlag("dockerfile", buildCmd.PersistentFlags().Lookup("dockerfile"))
+	config.BindFlag("nocache", buildCmd.PersistentFlags().Lookup("no-cache"))
+	config.BindFlag("push", buildCmd.PersistentFlags().Lookup("push"))
+	config.BindFlag("labels", buildCmd.PersistentFlags().Lookup("label"))
+	config.BindFlag("skipExisting", buildCmd.PersistentFlags().Lookup("skip-existing"))
+	config.BindFlag("identityTag", buildCmd.PersistentFlags().Lookup("identity-tag"))
+	config.BindFlag("skipTests", buildCmd.PersistentFlags().Lookup("skip-tests"))
+}
+
+// planBuild resolves everything needed to build (and optionally push) a single image without touching Docker
+func planBuild(cmd *cobra.Command, spec imageSpec, push bool) *report.PlannedImage {
+	repositoryFormat := viper.GetString("repository-format")
+	templateVars := imageTemplateVars(spec)
+	contextDir := spec.Context
+	dockerfile := imageDockerfileName(spec, templateVars)
+
+	buildArgs := resolveBuildArgs(cmd, templateVars, contextDir, dockerfile)
+	for k := range buildArgs {
+		if parser.IsSensitiveKey(k) {
+			ui.Warning("Build argument %s looks like a secret. Its value will be persisted in the image history.", k)
+		}
+	}
+
+	tagTemplates := viper.GetStringSlice("tags")
+	tags := repositoryNames(repositoryFormat, tagTemplates, templateVars)
+
+	identity := ""
+	if viper.GetBool("skipExisting") {
+		identity = repositoryNames(repositoryFormat, []string{viper.GetString("identityTag")}, templateVars)[0]
+		if !containsString(tags, identity) {
+			// the identity tag must be published, otherwise subsequent runs cannot detect the image
+			tags = append(tags, identity)
+		}
+	}
+
+	labelTemplates := viper.GetStringMapString("labels")
+	labels := map[string]string{}
+	for k, v := range labelTemplates {
+		labels[k] = renderTemplate(v, templateVars)
+		if parser.IsSensitiveKey(k) {
+			ui.AddSecret(labels[k])
+		}
+	}
+
+	return &report.PlannedImage{
+		Name:        spec.Name,
+		Context:     contextDir,
+		Dockerfile:  dockerfile,
+		ContextHash: templateVars["CONTEXT_HASH"],
+		BuildArgs:   buildArgs,
+		Labels:      labels,
+		NoCache:     viper.GetBool("nocache"),
+		Tags:        tags,
+		IdentityTag: identity,
+		Push:        push,
+		Registries:  planRegistries(tags),
+		Tests:       testCheckNames(testChecks()),
+	}
+}
+
+// resolveBuildArgs returns the build arguments of the --build-arg flag of cmd or, if none are given or cmd has no such flag,
+// of the configuration file. It sets the CONTEXT_HASH template variable if needed, see setContextHash.
+// Build arguments using %CONTEXT_HASH% enter the hash unrendered. Values of sensitive keys are registered as secrets.
+func resolveBuildArgs(cmd *cobra.Command, templateVars parser.TemplateVars, contextDir string, dockerfile string) map[string]string {
+	buildArgTemplates := buildArgTemplates(cmd)
+
+	buildArgs := map[string]string{}
+	hashedBuildArgs := map[string]string{}
+	for k, v := range buildArgTemplates {
+		hashedBuildArgs[k] = v
+		if !usesTemplateVar("CONTEXT_HASH", v) {
+			buildArgs[k] = renderTemplate(v, templateVars)
+			hashedBuildArgs[k] = buildArgs[k]
+		}
+	}
+	setContextHash(templateVars, contextDir, dockerfile, hashedBuildArgs)
+
+	for k, v := range buildArgTemplates {
+		if usesTemplateVar("CONTEXT_HASH", v) {
+			buildArgs[k] = renderTemplate(v, templateVars)
+		}
+		if parser.IsSensitiveKey(k) {
+			ui.AddSecret(buildArgs[k])
+		}
+	}
+	return buildArgs
+}
+
+// buildArgTemplates returns the unrendered build arguments of the --build-arg flag of cmd or of the configuration file
+func buildArgTemplates(cmd *cobra.Command) map[string]string {
+	buildArgTemplates := map[string]string{}
+	if cmd.Flags().Lookup("build-arg") != nil {
+		var err error
+		buildArgTemplates, err = cmd.Flags().GetStringToString("build-arg")
+		if err != nil {
+			ui.ErrorAndExit(1, err.Error())
+		}
+	}
+	if len(buildArgTemplates) == 0 {
+		var buildArgsFromConfig []types.KeyValueConfig
+
+		// unmarshal values into an interface as a workaround to enable case-sensitive data loading from config file
+		// ref: https://github.com/spf13/viper/issues/373
+		err := viper.UnmarshalKey("buildArgs", &buildArgsFromConfig)
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing build arguments from configuration file")
+		}
+
+		for _, v := range buildArgsFromConfig {
+			buildArgTemplates[v.Key] = v.Value
+		}
+	}
+	return buildArgTemplates
+}
+
+// setContextHash adds the CONTEXT_HASH template variable of an image if the repository format, tags, identity tag,
+// labels or build arguments use it. Hashing the context reads every file, so it is skipped otherwise.
+func setContextHash(templateVars parser.TemplateVars, contextDir string, dockerfile string, buildArgs map[string]string) {
+	hashTemplates := append([]string{viper.GetString("repository-format"), viper.GetString("identityTag")}, viper.GetStringSlice("tags")...)
+	for _, v := range viper.GetStringMapString("labels") {
+		hashTemplates = append(hashTemplates, v)
+	}
+	for _, v := range buildArgs {
+		hashTemplates = append(hashTemplates, v)
+	}
+	if !usesTemplateVar("CONTEXT_HASH", hashTemplates...) {
+		return
+	}
+
+	contextHash, err := imgtools.ContextHash(contextDir, dockerfile, buildArgs)
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed computing context hash")
+	}
+	templateVars["CONTEXT_HASH"] = contextHash
+}
+
+// buildImage builds and tests a single image, or reuses an existing one with --skip-existing. It returns the report
+// entry of the image, which is pushed by pushBuiltImage.
+func buildImage(cmd *cobra.Command, spec imageSpec, push bool, rep *report.Report) *report.Image {
+	ui.SetPhase("build")
+	ui.SetImage(spec.Name)
+	plan := planBuild(cmd, spec, push)
+	tags := plan.Tags
+
+	if viper.GetBool("verbose") {
+		registryName := viper.GetString("registry")
+		namespace := viper.GetString("namespace")
+		ui.Log("Used configuration:")
+		ui.Log("> repository name format: %s", viper.GetString("repository-format"))
+		ui.Log("> registry: %s", stringTernary(registryName == "", "<none>", registryName))
+		ui.Log("> namespace: %s", stringTernary(namespace == "", "<none>", namespace))
+		ui.Log("> base image name: %s", plan.Name)
+		ui.Log("> dockerfile: %s", plan.Dockerfile)
+		ui.Log("> context: %s", plan.Context)
+		ui.Log("> context hash: %s", stringTernary(plan.ContextHash == "", "<not computed>", plan.ContextHash))
+		ui.Log("> no-cache: %v", plan.NoCache)
+		ui.Log("> skip existing: %s", stringTernary(plan.IdentityTag != "", plan.IdentityTag, "<disabled>"))
+		ui.Log("> labels:%s", stringTernary(len(plan.Labels) == 0, " <none>", ""))
+		for k, v := range plan.Labels {
+			ui.Log("  - %s: %s", k, v)
+		}
+		ui.Log("> build args:%s", stringTernary(len(plan.BuildArgs) == 0, " <none>", ""))
+		for k, v := range plan.BuildArgs {
+			ui.Log("  - %s: %s", k, v)
+		}
+		ui.Log("> tags:%s", stringTernary(len(tags) == 0, " <none>", ""))
+		for _, v := range tags {
+			ui.Log("  - %s", v)
+		}
+	}
+
+	if len(tags) == 0 {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Abort. No valid image tag found.")
+	}
+
+	image := rep.AddImage(&report.Image{
+		Name:       plan.Name,
+		Context:    plan.Context,
+		Dockerfile: plan.Dockerfile,
+		BuildArgs:  plan.BuildArgs,
+		Labels:     plan.Labels,
+		Tags:       tags,
+	})
+
+	if plan.IdentityTag != "" {
+		identity := plan.IdentityTag
+		identityRef := registry.ParseReference(identity)
+		client := registry.NewClient(identityRef.Registry, registryCredentials(identityRef.Registry))
+		digest, err := client.HeadManifest(identityRef.Repository, identityRef.Ref())
+		switch err {
+		case nil:
+			ui.Info("Image %s already exists (%s). Skipping build and tests...", identity, digest)
+			image.Reused = true
+			image.ReusedFrom = identity
+			image.Digest = digest
+			return image
+		case registry.ErrNotFound:
+			ui.Log("Image %s does not exist yet", identity)
+		default:
+			ui.Log(err.Error())
+			ui.Warning("Failed checking whether %s exists. Proceed with building.", identity)
+		}
+	}
+
+	ui.StartGroup("Build " + plan.Name)
+	ui.Info("Building image...")
+	result, err := imgtools.Build(runContext, plan.Context, imgtools.BuildOptions{
+		Dockerfile: plan.Dockerfile,
+		BuildArgs:  plan.BuildArgs,
+		Tags:       tags,
+		Labels:     plan.Labels,
+		NoCache:    plan.NoCache,
+	})
+	recordBuildTimings(image, result)
+	if err != nil {
+		ui.Fail(err, stringTernary(failure.ClassOf(err) == failure.Cancelled, "Build cancelled", "Failed building image"))
+	}
+
+	image.Built = true
+	image.ImageID = result.ImageID
+	ui.EndGroup()
+	printCacheSummary(image)
+	for _, repository := range tags {
+		ui.Success(" > %s built succefully", repository)
+	}
+
+	if checks := testChecks(); len(checks) > 0 {
+		ui.SetPhase("test")
+		ui.StartGroup("Test " + plan.Name)
+		ui.Info("Testing image...")
+		passed := testImage(image, tags[0], checks)
+		ui.EndGroup()
+		if !passed {
+			ui.ErrorAndExit(failure.TestFailed.Code(), stringTernary(push, "Image tests failed. Not pushing.", "Image tests failed"))
+		}
+	}
+
+	return image
+}
+
+// pushBuiltImage pushes all tags of an image built by buildImage. The tags of a reused image are copied from the
+// existing image within the registries instead.
+func pushBuiltImage(image *report.Image) {
+	ui.SetPhase("push")
+	ui.SetImage(image.Name)
+	if image.Reused {
+		identityRef := registry.ParseReference(image.ReusedFrom)
+		client := registry.NewClient(identityRef.Registry, registryCredentials(identityRef.Registry))
+		copyImage(client, identityRef, image.Tags)
+		image.Pushed = true
+		image.PushedTags = image.Tags
+		return
+	}
+
+	ui.StartGroup("Push " + image.Name)
+	ui.Info("Pushing image...")
+	for _, repository := range image.Tags {
+		ui.SetTag(repository)
+		result := pushImage(repository)
+		image.Digest = stringTernary(result.Digest == "", image.Digest, result.Digest)
+		image.PushedTags = append(image.PushedTags, repository)
+		recordPushTiming(image, repository, result)
+		ui.Success(" > %s pushed succefully", repository)
+	}
+	ui.EndGroup()
+	image.Pushed = true
+}
+
+// usesTemplateVar reports whether any of the templates references the given template variable
+func usesTemplateVar(name string, templates ...string) bool {
+	for _, t := range templates {
+		if strings.Contains(t, "%"+name+"%") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 func stringTernary(condition bool, trueValue string, falseValue string) string {
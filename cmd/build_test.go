@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/types"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestResolveBuildArgsContextHash(t *testing.T) {
+	root := withWorkDir(t, map[string]string{"Dockerfile": "FROM scratch\n"}, ".")
+	viper.Set("buildArgs", []types.KeyValueConfig{
+		{Key: "HASH", Value: "hash-%CONTEXT_HASH%"},
+		{Key: "VERSION", Value: "1.0"},
+	})
+
+	templateVars := parser.TemplateVars{}
+	buildArgs := resolveBuildArgs(&cobra.Command{}, templateVars, root, "Dockerfile")
+
+	hash := templateVars["CONTEXT_HASH"]
+	if hash == "" {
+		t.Fatal("CONTEXT_HASH not set for a build argument using it")
+	}
+	if buildArgs["HASH"] != "hash-"+hash || buildArgs["VERSION"] != "1.0" {
+		t.Errorf("resolveBuildArgs() = %v", buildArgs)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+// runContext is cancelled when draide receives SIGINT or SIGTERM
+var runContext, cancelRun = context.WithCancel(context.Background())
+
+// forwardedSignals receives the signals while forwarding is enabled, e.g. to pass them on to a container
+var forwardedSignals = make(chan os.Signal, 4)
+
+var forwarding int32
+
+// handleSignals cancels the run context on the first SIGINT or SIGTERM and exits immediately on the second.
+// While forwarding is enabled, signals are passed on instead.
+func handleSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		for atomic.LoadInt32(&forwarding) == 1 {
+			forwardedSignals <- sig
+			sig = <-signals
+		}
+		ui.Warning("Received %s. Cancelling... Send it again to exit immediately.", sig)
+		cancelRun()
+
+		sig = <-signals
+		ui.Error("Received %s again. Exiting immediately.", sig)
+		os.Exit(failure.Cancelled.Code())
+	}()
+}
+
+// forwardSignals enables forwarding of signals and returns the channel they are passed on to
+func forwardSignals() <-chan os.Signal {
+	atomic.StoreInt32(&forwarding, 1)
+	return forwardedSignals
+}
+
+// checkCancelled exits if the run has been cancelled
+func checkCancelled() {
+	if err := runContext.Err(); err != nil {
+		ui.Fail(failure.Wrap(failure.Cancelled, err), "Cancelled")
+	}
+}
+
+// printCancelSummary lists what was completed before the run was cancelled
+func printCancelSummary() {
+	completed := []string{}
+	if runReport != nil {
+		for _, image := range runReport.Images {
+			if image.Built {
+				completed = append(completed, image.Name+" built")
+			}
+			if image.Reused {
+				completed = append(completed, image.Name+" reused from "+image.ReusedFrom)
+			}
+			for _, tag := range image.PushedTags {
+				completed = append(completed, tag+" pushed")
+			}
+		}
+	}
+
+	if len(completed) == 0 {
+		ui.Info("Cancelled before anything was completed")
+		return
+	}
+	ui.Info("Completed before cancellation:")
+	for _, v := range completed {
+		ui.Info(" > %s", v)
+	}
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/types"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Args:  cobra.NoArgs,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print every effective setting after configuration files, presets, environment variables (DRAIDE_*) and flags are merged.
+The YAML output lists the loaded configuration files in merge order, the applied presets and the matched rules in a header.
+The JSON output holds them in the configFiles, presets and matchedRules fields next to the settings field.
+Values of the password, of keys listed in secrets and of keys which look like credentials are masked.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			ui.ErrorAndExit(1, err.Error())
+		}
+
+		registerConfigSecrets()
+		settings := viper.AllSettings()
+		delete(settings, "presets")
+		delete(settings, "rules")
+		// secrets are masked before encoding, masking the encoded output would garble it
+		settings = maskSensitiveSettings("", ui.RedactValue(settings)).(map[string]interface{})
+
+		var content []byte
+		switch output {
+		case "yaml":
+			content, err = marshalYAML(settings)
+			header := ""
+			for _, f := range config.LoadedFragments() {
+				header += fmt.Sprintf("# configuration file: %s%s\n", f.Source, stringTernary(f.IncludedBy == "", "", " (included from "+f.IncludedBy+")"))
+			}
+			if presets := config.AppliedPresets(); len(presets) > 0 {
+				header += fmt.Sprintf("# applied presets: %s\n", strings.Join(presets, ", "))
+			}
+			if rules := config.MatchedRules(); len(rules) > 0 {
+				header += fmt.Sprintf("# matched rules: %s\n", strings.Join(rules, ", "))
+			}
+			content = append([]byte(header), content...)
+		case "json":
+			content, err = json.MarshalIndent(configDocument{
+				ConfigFiles:  append([]string{}, config.LoadedFiles()...),
+				Presets:      append([]string{}, config.AppliedPresets()...),
+				MatchedRules: append([]string{}, config.MatchedRules()...),
+				Settings:     settings,
+			}, "", "  ")
+			content = append(content, '\n')
+		default:
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unsupported output format: %s", output)
+		}
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed encoding configuration")
+		}
+
+		fmt.Fprint(ui.Stdout(), string(content))
+	},
+}
+
+var configExplainCmd = &cobra.Command{
+	Use:   "explain KEY",
+	Short: "Explain where the value of a setting comes from",
+	Long: `List every configuration layer which set KEY and the value it contributed, in the order the layers were applied.
+Layers are defaults, configuration files, presets, environment variables (DRAIDE_*) and flags.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registerConfigSecrets()
+		key := args[0]
+		contributions := config.Explain(key)
+		if len(contributions) == 0 && !viper.IsSet(key) {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unknown setting: %s", key)
+		}
+
+		width := 0
+		for _, c := range contributions {
+			if len(c.Source) > width {
+				width = len(c.Source)
+			}
+		}
+
+		fmt.Fprintln(ui.Stdout(), key)
+		for _, c := range contributions {
+			value := maskSensitiveSettings(key, c.Value)
+			fmt.Fprintln(ui.Stdout(), ui.Redact(fmt.Sprintf("  %-*s  %-6s  %v", width, c.Source, c.Mode, value)))
+		}
+		fmt.Fprintln(ui.Stdout(), ui.Redact(fmt.Sprintf("effective value: %v", maskSensitiveSettings(key, viper.Get(key)))))
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [FILE]",
+	Short: "Validate configuration files against the schema",
+	Long: `Validate FILE and the files it includes, or the configuration files in use, against the configuration schema.
+Unlike during regular runs, unknown keys are treated as errors. The command exits with a non-zero code if any issue is found.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fragments := config.LoadedFragments()
+		if len(args) > 0 {
+			var err error
+			fragments, err = config.ResolveFiles(args)
+			if perr, ok := err.(*config.ParseError); ok {
+				validateConfig(perr.Source, perr.Content, true)
+				ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid configuration")
+			} else if err != nil {
+				ui.ErrorAndExit(failure.ConfigInvalid.Code(), err.Error())
+			}
+		}
+		if len(fragments) == 0 {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "No configuration file found")
+		}
+
+		valid := true
+		for _, f := range fragments {
+			if validateConfig(f.Source, f.Content, true) {
+				ui.Success("Configuration file %s is valid", f.Source)
+			} else {
+				valid = false
+			}
+		}
+		if !valid {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid configuration")
+		}
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long:  `Print the JSON Schema of the configuration file, which editors can use for validation and autocompletion.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		content, err := json.MarshalIndent(config.RootSchema().JSONSchema(), "", "  ")
+		if err != nil {
+			ui.ErrorAndExit(1, err.Error())
+		}
+		fmt.Fprintln(ui.Stdout(), string(content))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configExplainCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+
+	configShowCmd.Flags().StringP("output", "o", "yaml", "Output format: yaml or json")
+}
+
+// validateConfig prints all schema issues of a configuration file and reports whether it is valid.
+// In strict mode unknown keys are errors, otherwise they are reported as warnings only.
+func validateConfig(source string, content []byte, strict bool) bool {
+	if !config.ValidationSupported(source) {
+		ui.Log("Skipping validation of %s. Only YAML and JSON files are supported.", source)
+		return true
+	}
+
+	valid := true
+	for _, issue := range config.ValidateContent(source, content) {
+		if issue.Severity == config.SeverityError || strict {
+			issue.Severity = config.SeverityError
+			ui.Error(issue.String())
+			valid = false
+		} else {
+			ui.Warning(issue.String())
+		}
+	}
+
+	return valid
+}
+
+// configDocument is the JSON output of config show
+type configDocument struct {
+	ConfigFiles  []string               `json:"configFiles"`
+	Presets      []string               `json:"presets"`
+	MatchedRules []string               `json:"matchedRules"`
+	Settings     map[string]interface{} `json:"settings"`
+}
+
+// maskSensitiveSettings masks the values of settings whose key is the password, is listed in secrets or looks like a
+// credential, as well as the values of key/value lists such as buildArgs with such a key. Unlike redaction of
+// registered secrets, this masks values of any length.
+func maskSensitiveSettings(name string, value interface{}) interface{} {
+	segment := name[strings.LastIndex(name, ".")+1:]
+	if name != "" && (parser.IsSensitiveKey(name) || parser.IsSensitiveKey(segment) || strings.EqualFold(segment, "password")) {
+		return maskValue(value)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		// entries of key/value lists, such as buildArgs and run.env, are masked by their key
+		if key, ok := v["key"].(string); ok {
+			if _, ok := v["value"]; ok {
+				for k, nested := range v {
+					masked[k] = nested
+				}
+				if parser.IsSensitiveKey(key) {
+					masked["value"] = maskValue(v["value"])
+				}
+				return masked
+			}
+		}
+		for k, nested := range v {
+			masked[k] = maskSensitiveSettings(strings.TrimPrefix(name+"."+k, "."), nested)
+		}
+		return masked
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			converted[fmt.Sprint(k)] = nested
+		}
+		return maskSensitiveSettings(name, converted)
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskSensitiveSettings(name, item)
+		}
+		return masked
+	}
+	return value
+}
+
+// maskValue replaces every non-empty scalar inside a value with the placeholder of secrets
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool:
+		return v
+	case string:
+		if v == "" {
+			return v
+		}
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			masked[k] = maskValue(nested)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskValue(item)
+		}
+		return masked
+	case []string:
+		masked := make([]string, len(v))
+		for i, item := range v {
+			masked[i] = maskValue(item).(string)
+		}
+		return masked
+	}
+	return ui.Redacted
+}
+
+// registerConfigSecrets masks the literal values of sensitive build arguments, labels and environment variables of the
+// configuration and its presets. Building and running register them when resolving the values, which config does not do.
+func registerConfigSecrets() {
+	prefixes := []string{""}
+	for name := range viper.GetStringMap("presets") {
+		prefixes = append(prefixes, "presets."+name+".")
+	}
+
+	for _, prefix := range prefixes {
+		for _, key := range []string{"buildArgs", "extraBuildArgs", "run.env"} {
+			var values []types.KeyValueConfig
+			if err := viper.UnmarshalKey(prefix+key, &values); err != nil {
+				ui.Log(err.Error())
+				continue
+			}
+			for _, v := range values {
+				if parser.IsSensitiveKey(v.Key) {
+					ui.AddSecret(v.Value)
+				}
+			}
+		}
+		for _, key := range []string{"labels", "extraLabels"} {
+			registerLabelSecrets("", viper.Get(prefix+key))
+		}
+	}
+}
+
+// registerLabelSecrets masks the values of sensitive labels. Viper splits label names at dots, so labels may be nested.
+func registerLabelSecrets(name string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			registerLabelSecrets(strings.TrimPrefix(name+"."+k, "."), nested)
+		}
+	case map[interface{}]interface{}:
+		for k, nested := range v {
+			registerLabelSecrets(strings.TrimPrefix(fmt.Sprintf("%s.%v", name, k), "."), nested)
+		}
+	case string:
+		if parser.IsSensitiveKey(name) {
+			ui.AddSecret(v)
+		}
+	}
+}
+
+// marshalYAML encodes a value as YAML with the two space indentation of configuration files
+func marshalYAML(value interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/viper"
+)
+
+func TestMaskSensitiveSettings(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("secrets", []string{"deployPin"})
+
+	settings := map[string]interface{}{
+		"password":  "xyz",
+		"username":  "user",
+		"deploypin": 1234,
+		"secrets":   []interface{}{"deployPin"},
+		"buildargs": []interface{}{
+			map[string]interface{}{"key": "NPM_TOKEN", "value": "t"},
+			map[string]interface{}{"key": "VERSION", "value": "1.0"},
+		},
+		"labels": map[string]interface{}{"api_key": "k", "team": "core"},
+		"run":    map[string]interface{}{"env": []interface{}{map[string]interface{}{"key": "DB_PASSWORD", "value": "pw"}}},
+	}
+	want := map[string]interface{}{
+		"password":  ui.Redacted,
+		"username":  "user",
+		"deploypin": ui.Redacted,
+		"secrets":   []interface{}{"deployPin"},
+		"buildargs": []interface{}{
+			map[string]interface{}{"key": "NPM_TOKEN", "value": ui.Redacted},
+			map[string]interface{}{"key": "VERSION", "value": "1.0"},
+		},
+		"labels": map[string]interface{}{"api_key": ui.Redacted, "team": "core"},
+		"run":    map[string]interface{}{"env": []interface{}{map[string]interface{}{"key": "DB_PASSWORD", "value": ui.Redacted}}},
+	}
+
+	if got := maskSensitiveSettings("", settings); !reflect.DeepEqual(got, want) {
+		t.Errorf("maskSensitiveSettings() = %v, want %v", got, want)
+	}
+	if got := maskSensitiveSettings("password", ""); got != "" {
+		t.Errorf("maskSensitiveSettings() of an empty password = %q, want it empty", got)
+	}
+}
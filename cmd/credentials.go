@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/pkg/credstore"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/registry"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// initCredentials resolves username and password with the following precedence (highest first):
+// --password-stdin, --password-file, --password / --username flag, DRAIDE_PASSWORD / DRAIDE_USERNAME environment variable, configuration file
+func initCredentials() {
+	passwordStdIn, err := rootCmd.PersistentFlags().GetBool("password-stdin")
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	passwordFile, err := rootCmd.PersistentFlags().GetString("password-file")
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	if passwordStdIn && passwordFile != "" {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "--password-stdin and --password-file are mutually exclusive")
+	}
+
+	usernameSource := credentialSource("username", "username", "DRAIDE_USERNAME")
+	passwordSource := credentialSource("password", "password", "DRAIDE_PASSWORD")
+
+	if passwordStdIn {
+		viper.Set("password", readPasswordFromStdin())
+		passwordSource = "stdin"
+	} else if passwordFile != "" {
+		viper.Set("password", readPasswordFromFile(passwordFile))
+		passwordSource = "file " + passwordFile
+	}
+	if passwordStdIn || passwordFile != "" {
+		config.RecordLayer(passwordSource, "set", map[string]interface{}{"password": ui.Redacted})
+	}
+
+	// check credentials completeness
+	username := viper.GetString("username")
+	password := viper.GetString("password")
+	ui.AddSecret(password)
+	if (username == "" && password != "") || (username != "" && password == "") {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Incomplete credentials. Username and password information must be provided.")
+	}
+
+	if ui.IsVerbose() && username != "" && password != "" {
+		ui.Log("Credentials:")
+		ui.Log(" > username: %s (from %s)", ui.Redacted, usernameSource)
+		ui.Log(" > password: %s (from %s)", ui.Redacted, passwordSource)
+	}
+}
+
+// credentialSource describes where the current value of a credential setting originates from
+func credentialSource(key string, flagName string, envVar string) string {
+	if rootCmd.PersistentFlags().Changed(flagName) {
+		return "--" + flagName + " flag"
+	}
+	if _, ok := os.LookupEnv(envVar); ok {
+		return "environment variable " + envVar
+	}
+	if viper.InConfig(key) {
+		return "configuration file"
+	}
+	return "<none>"
+}
+
+func readPasswordFromStdin() string {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	// accept anything but an interactive terminal: pipes, redirected files, here-strings and sockets
+	if fi.Mode()&os.ModeCharDevice != 0 {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "No password from stdin received. Stdin is a terminal.")
+	}
+
+	scanner := bufio.NewScanner(bufio.NewReader(os.Stdin))
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed reading password from stdin")
+	}
+	password := strings.TrimSuffix(scanner.Text(), "\r")
+
+	if password == "" {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Found empty string as password from stdin")
+	}
+
+	return password
+}
+
+func readPasswordFromFile(path string) string {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing password file path")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed reading password file %s", path)
+	}
+	password := strings.TrimRight(string(content), "\r\n")
+
+	if password == "" {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Found empty string as password in file %s", path)
+	}
+
+	return password
+}
+
+// registryCredentials returns the credentials to use for a registry.
+// Explicitly supplied credentials take precedence over credentials stored via `draide login`.
+func registryCredentials(registryName string) registry.Credentials {
+	creds, _ := registryCredentialSource(registryName)
+	return creds
+}
+
+// registryCredentialSource returns the credentials to use for a registry together with a description of where they come from
+func registryCredentialSource(registryName string) (registry.Credentials, string) {
+	if username := viper.GetString("username"); username != "" {
+		return registry.Credentials{Username: username, Password: viper.GetString("password")}, credentialSource("username", "username", "DRAIDE_USERNAME")
+	}
+
+	creds := storedCredentials(registryName)
+	if creds.Username == "" && creds.Password == "" {
+		return creds, "no credentials"
+	}
+	return creds, "stored credentials"
+}
+
+// storedCredentials returns the credentials stored via `draide login` for a registry
+func storedCredentials(registryName string) registry.Credentials {
+	creds, err := credstore.Get(registryName)
+	if err != nil {
+		ui.Log(err.Error())
+		ui.Warning("Failed reading stored credentials for %s", registryName)
+	}
+	ui.AddSecret(creds.Password)
+	return creds
+}
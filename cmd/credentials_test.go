@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// withCredentialSources sets the password flags, environment variables and stdin of a test, binding them like the
+// root command does
+func withCredentialSources(t *testing.T, flags map[string]string, env map[string]string, stdin string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "draide-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	viper.Reset()
+	viper.BindPFlag("username", rootCmd.PersistentFlags().Lookup("username"))
+	viper.BindPFlag("password", rootCmd.PersistentFlags().Lookup("password"))
+	viper.BindEnv("username", "DRAIDE_USERNAME")
+	viper.BindEnv("password", "DRAIDE_PASSWORD")
+	t.Cleanup(viper.Reset)
+
+	for name, value := range flags {
+		flag := rootCmd.PersistentFlags().Lookup(name)
+		defaultValue := flag.DefValue
+		if err := flag.Value.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		flag.Changed = true
+		t.Cleanup(func() {
+			flag.Value.Set(defaultValue)
+			flag.Changed = false
+		})
+	}
+
+	for name, value := range env {
+		name := name
+		previous, set := os.LookupEnv(name)
+		os.Setenv(name, value)
+		t.Cleanup(func() {
+			if set {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+
+	if stdin != "" {
+		path := filepath.Join(dir, "stdin")
+		if err := ioutil.WriteFile(path, []byte(stdin), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := os.Stdin
+		os.Stdin = f
+		t.Cleanup(func() {
+			os.Stdin = previous
+			f.Close()
+		})
+	}
+}
+
+// writePasswordFile writes a password file and returns its path
+func writePasswordFile(t *testing.T, content string) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "draide-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return f.Name()
+}
+
+func TestInitCredentialsPrecedence(t *testing.T) {
+	env := map[string]string{"DRAIDE_USERNAME": "alice", "DRAIDE_PASSWORD": "env-password"}
+
+	tests := []struct {
+		name  string
+		flags map[string]string
+		env   map[string]string
+		stdin string
+		file  string
+		// config is the password of the configuration file
+		config string
+		want   string
+	}{
+		{name: "configuration file", env: map[string]string{"DRAIDE_USERNAME": "alice", "DRAIDE_PASSWORD": ""}, config: "config-password", want: "config-password"},
+		{name: "environment over configuration file", env: env, config: "config-password", want: "env-password"},
+		{name: "environment", env: env, want: "env-password"},
+		{name: "flag over environment", flags: map[string]string{"password": "flag-password"}, env: env, want: "flag-password"},
+		{name: "file over flag", flags: map[string]string{"password": "flag-password"}, env: env, file: "file-password\n", want: "file-password"},
+		{name: "file with CRLF", env: env, file: "file-password\r\n", want: "file-password"},
+		{name: "file keeps other whitespace", env: env, file: " file password \n\n", want: " file password "},
+		{name: "stdin over flag", flags: map[string]string{"password": "flag-password", "password-stdin": "true"}, env: env, stdin: "stdin-password\n", want: "stdin-password"},
+		{name: "stdin reads the first line", flags: map[string]string{"password-stdin": "true"}, env: env, stdin: "stdin-password\r\nsecond line\n", want: "stdin-password"},
+		{name: "stdin without newline", flags: map[string]string{"password-stdin": "true"}, env: env, stdin: "stdin-password", want: "stdin-password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := map[string]string{}
+			for k, v := range tt.flags {
+				flags[k] = v
+			}
+			if tt.file != "" {
+				flags["password-file"] = writePasswordFile(t, tt.file)
+			}
+			withCredentialSources(t, flags, tt.env, tt.stdin)
+			if tt.config != "" {
+				viper.MergeConfigMap(map[string]interface{}{"password": tt.config})
+			}
+
+			initCredentials()
+
+			if got := viper.GetString("password"); got != tt.want {
+				t.Errorf("password = %q, want %q", got, tt.want)
+			}
+			if got := viper.GetString("username"); got != "alice" {
+				t.Errorf("username = %q, want alice", got)
+			}
+		})
+	}
+}
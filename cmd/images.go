@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/gittools"
+	"github.com/marcelriegr/draide/pkg/registry"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// imageSpec describes an image to build, either given by the CONTEXT_DIR argument or declared in the `images` configuration
+type imageSpec struct {
+	Name       string
+	Context    string
+	Dockerfile string
+	WatchPaths []string `mapstructure:"watchPaths"`
+	DependsOn  []string `mapstructure:"dependsOn"`
+}
+
+var fromInstructionPattern = regexp.MustCompile(`(?im)^\s*FROM\s+(?:--\S+\s+)*(\S+)`)
+
+// resolveImageSpecs returns the images to build in dependency order.
+// Relative context directories and watch paths of the images section are relative to the configuration file declaring
+// them, so that the result does not depend on the directory draide runs in. CONTEXT_DIR is relative to the working directory.
+func resolveImageSpecs(args []string) []imageSpec {
+	var specs []imageSpec
+
+	if len(args) > 0 {
+		contextDir, err := resolvePath("", args[0])
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing context directory path")
+		}
+		specs = []imageSpec{{
+			Name:       viper.GetString("imagename"),
+			Context:    contextDir,
+			WatchPaths: resolvePaths(config.SettingDir("watchPaths"), viper.GetStringSlice("watchPaths")),
+		}}
+	} else {
+		if !viper.IsSet("images") {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Missing CONTEXT_DIR. Either pass a context directory or declare images in the configuration file.")
+		}
+		err := viper.UnmarshalKey("images", &specs)
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing images from configuration file")
+		}
+	}
+
+	baseDir := config.SettingDir("images")
+	for i := range specs {
+		if len(args) == 0 {
+			contextDir, err := resolvePath(baseDir, specs[i].Context)
+			if err != nil {
+				ui.Log(err.Error())
+				ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing context directory path of image %s", specs[i].Name)
+			}
+			specs[i].Context = contextDir
+			specs[i].WatchPaths = resolvePaths(baseDir, specs[i].WatchPaths)
+		}
+		if specs[i].Name == "" {
+			specs[i].Name = filepath.Base(specs[i].Context)
+		}
+	}
+
+	sorted, err := sortImageSpecs(specs)
+	if err != nil {
+		ui.Fail(err, "Failed ordering images: %s", err.Error())
+	}
+	return sorted
+}
+
+// resolvePath returns the absolute path of a path relative to baseDir, or to the working directory if baseDir is empty
+func resolvePath(baseDir string, path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Abs(path)
+}
+
+// resolvePaths resolves watch paths like resolvePath. Paths which cannot be resolved are kept, they never match a change.
+func resolvePaths(baseDir string, paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		if abs, err := resolvePath(baseDir, path); err == nil {
+			path = abs
+		}
+		resolved[i] = path
+	}
+	return resolved
+}
+
+// applyImageConfig applies the configuration files of an image's context directory which are not loaded yet, such as
+// a service-level .draide.yaml of an image declared in the images section. The returned function restores the configuration.
+func applyImageConfig(spec imageSpec) func() {
+	files, err := config.DiscoverFiles(spec.Context, "")
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed searching configuration files of image %s", spec.Name)
+	}
+
+	overlays := []string{}
+	for _, file := range files {
+		if !containsString(config.LoadedFiles(), file) {
+			overlays = append(overlays, file)
+		}
+	}
+
+	fragments, err := config.ResolveFiles(overlays)
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed reading configuration files of image %s", spec.Name)
+	}
+	for _, f := range fragments {
+		if !validateConfig(f.Source, f.Content, false) {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid configuration file")
+		}
+		ui.Log("Using configuration file for image %s: %s", spec.Name, f.Source)
+	}
+
+	return config.ApplyOverlay(fragments)
+}
+
+// imageTemplateVars returns the template variables of an image
+func imageTemplateVars(spec imageSpec) parser.TemplateVars {
+	templateVars := parser.GenerateTemplateVars(parser.GenerateTemplateVarsOptions{ContextDir: spec.Context})
+	templateVars["IMAGE_NAME"] = spec.Name
+	return templateVars
+}
+
+// imageDockerfileName returns the path of an image's Dockerfile relative to its context directory, using forward slashes
+func imageDockerfileName(spec imageSpec, templateVars parser.TemplateVars) string {
+	dockerfile := stringTernary(spec.Dockerfile == "", viper.GetString("dockerfile"), spec.Dockerfile)
+	return filepath.ToSlash(renderTemplate(dockerfile, templateVars))
+}
+
+// imageDockerfile returns the absolute path of an image's Dockerfile
+func imageDockerfile(spec imageSpec) string {
+	return filepath.Join(spec.Context, filepath.FromSlash(imageDockerfileName(spec, imageTemplateVars(spec))))
+}
+
+// imageRepositories returns the repository of every image by name, as referenced by FROM instructions of other images.
+// Template variables are computed once per image, as this reads the git repository.
+func imageRepositories(specs []imageSpec) map[string]registry.Reference {
+	repositories := map[string]registry.Reference{}
+	for _, spec := range specs {
+		repository := repositoryNames(viper.GetString("repository-format"), []string{"latest"}, imageTemplateVars(spec))[0]
+		repositories[spec.Name] = registry.ParseReference(repository)
+	}
+	return repositories
+}
+
+// imageDependencies returns the names of other images the given image is based on.
+// Dependencies are either declared via `dependsOn` or detected from FROM instructions referencing the repository of another image.
+func imageDependencies(spec imageSpec, specs []imageSpec, repositories map[string]registry.Reference) []string {
+	dependencies := append([]string{}, spec.DependsOn...)
+
+	content, err := ioutil.ReadFile(imageDockerfile(spec))
+	if err != nil {
+		return dependencies
+	}
+
+	for _, match := range fromInstructionPattern.FindAllStringSubmatch(string(content), -1) {
+		from := registry.ParseReference(match[1])
+		for _, other := range specs {
+			if other.Name == spec.Name {
+				continue
+			}
+			ref := repositories[other.Name]
+			if ref.Registry == from.Registry && ref.Repository == from.Repository && !containsString(dependencies, other.Name) {
+				dependencies = append(dependencies, other.Name)
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// sortImageSpecs orders images so that base images are built before the images depending on them.
+// Cyclic dependencies and a `dependsOn` naming an image which is not declared are config-invalid failures.
+func sortImageSpecs(specs []imageSpec) ([]imageSpec, error) {
+	sorted := make([]imageSpec, 0, len(specs))
+	state := map[string]int{} // 1: visiting, 2: done
+	byName := map[string]imageSpec{}
+	for _, spec := range specs {
+		byName[spec.Name] = spec
+	}
+	for _, spec := range specs {
+		for _, dependency := range spec.DependsOn {
+			if _, ok := byName[dependency]; !ok {
+				return nil, failure.New(failure.ConfigInvalid, "image %s depends on unknown image %s", spec.Name, dependency)
+			}
+		}
+	}
+
+	repositories := imageRepositories(specs)
+
+	var visit func(spec imageSpec, chain []string) error
+	visit = func(spec imageSpec, chain []string) error {
+		switch state[spec.Name] {
+		case 1:
+			return failure.New(failure.ConfigInvalid, "cyclic image dependency: %s", strings.Join(append(chain, spec.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[spec.Name] = 1
+		for _, dependency := range imageDependencies(spec, specs, repositories) {
+			if err := visit(byName[dependency], append(chain, spec.Name)); err != nil {
+				return err
+			}
+		}
+		state[spec.Name] = 2
+		sorted = append(sorted, spec)
+		return nil
+	}
+
+	for _, spec := range specs {
+		if err := visit(spec, []string{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// changedImageSpecs filters images whose inputs changed since a git revision, including images based on changed images.
+// If since is empty, the merge-base with the default branch is used.
+func changedImageSpecs(specs []imageSpec, since string) []imageSpec {
+	changeSet, err := gittools.ChangedFiles(".", since)
+	if err != nil {
+		ui.Fail(err, "Failed detecting changed files: %s", err.Error())
+	}
+	ui.Log("Found %d changed files since %s", len(changeSet.Files), changeSet.Base)
+
+	return selectChangedImageSpecs(specs, changeSet)
+}
+
+// selectChangedImageSpecs filters images whose context, Dockerfile or watch paths are part of the change set, including
+// images based on changed images. The specs must be sorted by dependencies.
+func selectChangedImageSpecs(specs []imageSpec, changeSet *gittools.ChangeSet) []imageSpec {
+	changed := map[string]bool{}
+	for _, spec := range specs {
+		inputs := []string{spec.Context, imageDockerfile(spec)}
+		inputs = append(inputs, spec.WatchPaths...)
+		for _, input := range inputs {
+			if pathChanged(changeSet, input) {
+				ui.Log("Image %s changed: %s", spec.Name, input)
+				changed[spec.Name] = true
+				break
+			}
+		}
+	}
+
+	// specs are sorted by dependencies, so a single pass propagates changes of base images
+	repositories := imageRepositories(specs)
+	selected := []imageSpec{}
+	for _, spec := range specs {
+		for _, dependency := range imageDependencies(spec, specs, repositories) {
+			if changed[dependency] && !changed[spec.Name] {
+				ui.Log("Image %s changed: base image %s changed", spec.Name, dependency)
+				changed[spec.Name] = true
+			}
+		}
+		if changed[spec.Name] {
+			selected = append(selected, spec)
+		} else {
+			ui.Log("Image %s unchanged. Skipping.", spec.Name)
+		}
+	}
+
+	return selected
+}
+
+// pathChanged reports whether a file or any file inside a directory is part of the change set
+func pathChanged(changeSet *gittools.ChangeSet, path string) bool {
+	path, _ = homedir.Expand(path)
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(changeSet.Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, file := range changeSet.Files {
+		if rel == "." || file == rel || strings.HasPrefix(file, rel+"/") {
+			return true
+		}
+	}
+	return false
+}
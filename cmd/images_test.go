@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/gittools"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// withWorkDir creates a directory tree of files with the given content and changes into dir inside of it
+func withWorkDir(t *testing.T, files map[string]string, dir string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "draide-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ = filepath.EvalSymlinks(root)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, filepath.FromSlash(dir))); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		viper.Reset()
+	})
+	return root
+}
+
+// loadConfig loads the configuration files applying to the working directory, without the user-level file
+func loadConfig(t *testing.T) {
+	t.Helper()
+	files, err := config.DiscoverFiles(".", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments, err := config.ResolveFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadFragments(fragments); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveImageSpecsFromNestedDirectory(t *testing.T) {
+	root := withWorkDir(t, map[string]string{
+		".draide.yaml":         "images:\n  - context: svc/a\n    watchPaths: [lib, /abs]\n  - name: b\n    context: ./svc/b\n",
+		"svc/a/Dockerfile":     "FROM scratch\n",
+		"svc/b/Dockerfile":     "FROM scratch\n",
+		"svc/a/src/main.go":    "package main\n",
+		"svc/b/.draide.yaml":   "tags: [dev]\n",
+		"lib/shared/README.md": "shared\n",
+	}, "svc/a/src")
+	if _, err := git.PlainInit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	loadConfig(t)
+
+	specs := resolveImageSpecs(nil)
+	want := []imageSpec{
+		{Name: "a", Context: filepath.Join(root, "svc", "a"), WatchPaths: []string{filepath.Join(root, "lib"), "/abs"}},
+		{Name: "b", Context: filepath.Join(root, "svc", "b"), WatchPaths: []string{}},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("resolveImageSpecs() = %+v, want %+v", specs, want)
+	}
+}
+
+func TestResolveImageSpecsContextDir(t *testing.T) {
+	root := withWorkDir(t, map[string]string{
+		".draide.yaml":     "watchPaths: [lib]\n",
+		"svc/a/Dockerfile": "FROM scratch\n",
+	}, "svc")
+	if _, err := git.PlainInit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	loadConfig(t)
+
+	specs := resolveImageSpecs([]string{"a"})
+	want := []imageSpec{{Name: "a", Context: filepath.Join(root, "svc", "a"), WatchPaths: []string{filepath.Join(root, "lib")}}}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("resolveImageSpecs() = %+v, want %+v", specs, want)
+	}
+}
+
+// imageNames returns the names of images in order
+func imageNames(specs []imageSpec) []string {
+	names := []string{}
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+func TestSortImageSpecs(t *testing.T) {
+	root := withWorkDir(t, map[string]string{
+		"base/Dockerfile":    "FROM alpine\n",
+		"app/Dockerfile":     "FROM registry.example.com/team/base:latest AS build\nFROM scratch\n",
+		"tools/Dockerfile":   "FROM --platform=linux/amd64 registry.example.com/team/app\n",
+		"docs/Dockerfile":    "FROM scratch\n",
+		"cycle-a/Dockerfile": "FROM registry.example.com/team/cycle-b\n",
+		"cycle-b/Dockerfile": "FROM scratch\n",
+	}, ".")
+	viper.Set("repository-format", "%REGISTRY%/%NAMESPACE%/%IMAGE_NAME%")
+	viper.Set("registry", "registry.example.com")
+	viper.Set("namespace", "team")
+	viper.Set("dockerfile", "Dockerfile")
+
+	spec := func(name string, dependsOn ...string) imageSpec {
+		return imageSpec{Name: name, Context: filepath.Join(root, name), DependsOn: dependsOn}
+	}
+
+	tests := []struct {
+		name  string
+		specs []imageSpec
+		want  []string
+		err   string
+	}{
+		{
+			name:  "FROM instructions",
+			specs: []imageSpec{spec("tools"), spec("app"), spec("base")},
+			want:  []string{"base", "app", "tools"},
+		},
+		{
+			name:  "dependsOn",
+			specs: []imageSpec{spec("app"), spec("docs", "tools"), spec("tools"), spec("base")},
+			want:  []string{"base", "app", "tools", "docs"},
+		},
+		{
+			name:  "independent images keep their order",
+			specs: []imageSpec{spec("docs"), spec("base")},
+			want:  []string{"docs", "base"},
+		},
+		{
+			name:  "cycle",
+			specs: []imageSpec{spec("cycle-a"), spec("cycle-b", "cycle-a")},
+			err:   "cyclic image dependency: cycle-a -> cycle-b -> cycle-a",
+		},
+		{
+			name:  "self dependency",
+			specs: []imageSpec{spec("docs", "docs")},
+			err:   "cyclic image dependency: docs -> docs",
+		},
+		{
+			name:  "unknown dependsOn",
+			specs: []imageSpec{spec("docs", "missing")},
+			err:   "image docs depends on unknown image missing",
+		},
+	}
+	for _, tt := range tests {
+		sorted, err := sortImageSpecs(tt.specs)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err || failure.ClassOf(err) != failure.ConfigInvalid {
+				t.Errorf("%s: sortImageSpecs() error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: sortImageSpecs() error = %v", tt.name, err)
+			continue
+		}
+		if got := imageNames(sorted); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: sortImageSpecs() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectChangedImageSpecs(t *testing.T) {
+	root := withWorkDir(t, map[string]string{
+		"base/Dockerfile": "FROM alpine\n",
+		"app/Dockerfile":  "FROM registry.example.com/team/base\n",
+		"docs/Dockerfile": "FROM scratch\n",
+		"web/Dockerfile":  "FROM scratch\n",
+	}, ".")
+	viper.Set("repository-format", "%REGISTRY%/%NAMESPACE%/%IMAGE_NAME%")
+	viper.Set("registry", "registry.example.com")
+	viper.Set("namespace", "team")
+	viper.Set("dockerfile", "Dockerfile")
+
+	specs := []imageSpec{
+		{Name: "base", Context: filepath.Join(root, "base")},
+		{Name: "app", Context: filepath.Join(root, "app")},
+		{Name: "docs", Context: filepath.Join(root, "docs"), Dockerfile: "../shared/docs.Dockerfile"},
+		{Name: "web", Context: filepath.Join(root, "web"), WatchPaths: []string{filepath.Join(root, "lib")}},
+		{Name: "api", Context: filepath.Join(root, "api"), DependsOn: []string{"web"}},
+	}
+
+	tests := []struct {
+		files []string
+		want  []string
+	}{
+		{files: []string{}, want: []string{}},
+		{files: []string{"README.md"}, want: []string{}},
+		{files: []string{"app/main.go"}, want: []string{"app"}},
+		// changes of a base image propagate to the images depending on it
+		{files: []string{"base/Dockerfile"}, want: []string{"base", "app"}},
+		{files: []string{"shared/docs.Dockerfile"}, want: []string{"docs"}},
+		{files: []string{"lib/util.go"}, want: []string{"web", "api"}},
+		// sibling directories sharing a prefix are no change of the image
+		{files: []string{"library/util.go", "web2/Dockerfile", "apis/main.go"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		changeSet := &gittools.ChangeSet{Root: root, Files: tt.files}
+		if got := imageNames(selectChangedImageSpecs(specs, changeSet)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectChangedImageSpecs(%v) = %v, want %v", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestPathChanged(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	changeSet := &gittools.ChangeSet{Root: root, Files: []string{"svc/a/main.go", "..data/file"}}
+
+	tests := map[string]bool{
+		"/repo":                true,
+		"/repo/svc":            true,
+		"/repo/svc/a":          true,
+		"/repo/svc/a/main.go":  true,
+		"/repo/svc/a/other.go": false,
+		"/repo/svc/ab":         false,
+		"/repo/..data":         true,
+		"/other":               false,
+		"/":                    false,
+	}
+	for path, want := range tests {
+		if got := pathChanged(changeSet, filepath.FromSlash(path)); got != want {
+			t.Errorf("pathChanged(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/gittools"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// initSettings holds the values written into a new configuration file
+type initSettings struct {
+	ImageName  string
+	Registry   string
+	Namespace  string
+	Tags       []string
+	Dockerfile string
+	Images     []imageSpec
+}
+
+// initSkippedDirs are directories not searched for Dockerfiles
+var initSkippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+var initConfigTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(
+	`# draide configuration, generated by ` + "`draide init`" + `
+# Run ` + "`draide config schema`" + ` for a description of all settings.
+{{- if not .Images}}
+
+# Image name used when building a CONTEXT_DIR
+imageName: {{quote .ImageName}}
+{{- if .Dockerfile}}
+dockerfile: {{quote .Dockerfile}}
+{{- end}}
+{{- end}}
+
+# Images are named %REGISTRY%/%NAMESPACE%/%IMAGE_NAME%:<tag>
+{{- if .Registry}}
+registry: {{quote .Registry}}
+{{- else}}
+# registry: "docker.io"
+{{- end}}
+{{- if .Namespace}}
+namespace: {{quote .Namespace}}
+{{- else}}
+# namespace: "my-team"
+{{- end}}
+
+# Tags may contain template variables, see ` + "`draide --help`" + `
+tags:
+{{- range .Tags}}
+  - {{quote .}}
+{{- end}}
+{{- if .Images}}
+
+# Images built by ` + "`draide build`" + ` when no CONTEXT_DIR is given
+images:
+{{- range .Images}}
+  - name: {{quote .Name}}
+    context: {{quote .Context}}
+{{- if .Dockerfile}}
+    dockerfile: {{quote .Dockerfile}}
+{{- end}}
+{{- end}}
+{{- end}}
+
+# Presets are applied via --preset, e.g. ` + "`draide build --preset ci`" + `
+presets:
+  ci:
+    reportFile: "draide-report.json"
+    extraLabels:
+      org.opencontainers.image.revision: "%COMMIT_HASH%"
+  release:
+    extends: ci
+    nocache: true
+    extraTags:
+      - "%BRANCH%-%SHORT_COMMIT_HASH%"
+`))
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a configuration file for the current repository",
+	Long: `Create a .draide.yaml in the current directory.
+
+Dockerfiles in the directory tree are declared as images, the image name defaults to the name of the current directory
+and registry and namespace are inferred from the git remote origin (GitHub and GitLab).
+Values given via --name, --registry, --namespace and --tag take precedence over detected ones.
+When run in a terminal, every value is confirmed interactively.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("file")
+		force, _ := cmd.Flags().GetBool("force")
+		nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+
+		if _, err := os.Stat(path); err == nil && !force {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "%s already exists. Use --force to overwrite it.", path)
+		}
+
+		settings := detectInitSettings(cmd)
+		if !nonInteractive && term.IsTerminal(os.Stdin.Fd()) {
+			promptInitSettings(&settings)
+		}
+
+		var content bytes.Buffer
+		if err := initConfigTemplate.Execute(&content, settings); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed generating configuration")
+		}
+		if err := ioutil.WriteFile(path, content.Bytes(), 0644); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed writing %s", path)
+		}
+
+		ui.Success("Created %s", path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().String("file", ".draide.yaml", "Path of the configuration file to create")
+	initCmd.Flags().Bool("force", false, "Overwrite an existing configuration file")
+	initCmd.Flags().Bool("non-interactive", false, "Use detected values without prompting")
+}
+
+// detectInitSettings collects configuration values from flags, the directory tree and the git remote
+func detectInitSettings(cmd *cobra.Command) initSettings {
+	settings := initSettings{
+		ImageName: viper.GetString("imagename"),
+		Tags:      []string{"latest", "%BRANCH%", "%SHORT_COMMIT_HASH%"},
+	}
+	if cmd.Flags().Changed("tag") {
+		settings.Tags = viper.GetStringSlice("tags")
+	}
+
+	if remoteURL, err := gittools.GetRemoteURL(".", "origin"); err == nil {
+		settings.Registry, settings.Namespace = registryFromRemote(remoteURL)
+		ui.Log("Inferred registry %q and namespace %q from remote %s", settings.Registry, settings.Namespace, remoteURL)
+	} else {
+		ui.Log("Unable to read git remote origin: %s", err.Error())
+	}
+	if registry := viper.GetString("registry"); registry != "" {
+		settings.Registry = registry
+	}
+	if namespace := viper.GetString("namespace"); namespace != "" {
+		settings.Namespace = namespace
+	}
+
+	dockerfiles, err := findDockerfiles(".")
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed searching Dockerfiles")
+	}
+	switch {
+	case len(dockerfiles) == 0:
+		ui.Warning("No Dockerfile found")
+	case len(dockerfiles) == 1 && filepath.Dir(dockerfiles[0]) == ".":
+		settings.Dockerfile = stringTernary(dockerfiles[0] == "Dockerfile", "", dockerfiles[0])
+	default:
+		settings.Images = initImageSpecs(dockerfiles, settings.ImageName)
+	}
+
+	return settings
+}
+
+// findDockerfiles returns the paths of all Dockerfiles below root, relative to root
+func findDockerfiles(root string) ([]string, error) {
+	dockerfiles := []string{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || initSkippedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDockerfileName(name) {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			dockerfiles = append(dockerfiles, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	sort.Strings(dockerfiles)
+	return dockerfiles, err
+}
+
+func isDockerfileName(name string) bool {
+	if strings.HasSuffix(name, ".dockerignore") {
+		return false
+	}
+	return name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile")
+}
+
+// initImageSpecs declares an image per Dockerfile, named after its directory and the Dockerfile's suffix if any
+func initImageSpecs(dockerfiles []string, rootName string) []imageSpec {
+	specs := []imageSpec{}
+
+	for _, dockerfile := range dockerfiles {
+		dir, file := filepath.Split(dockerfile)
+		dir = strings.TrimSuffix(dir, "/")
+
+		spec := imageSpec{Name: rootName, Context: "."}
+		if dir != "" {
+			spec.Name = filepath.Base(dir)
+			spec.Context = "./" + dir
+		}
+		if file != "Dockerfile" {
+			spec.Dockerfile = file
+			variant := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(file, "Dockerfile"), "."), ".Dockerfile")
+			if variant != "" {
+				spec.Name += "-" + strings.ToLower(variant)
+			}
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// registryFromRemote infers the container registry and namespace of the git hosting service a remote URL points to
+func registryFromRemote(remoteURL string) (string, string) {
+	var host, path string
+
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", ""
+		}
+		host, path = u.Hostname(), u.Path
+	} else if i := strings.Index(remoteURL, ":"); i >= 0 {
+		// scp-like syntax, such as git@github.com:owner/repo.git
+		host, path = remoteURL[:i], remoteURL[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return "", ""
+	}
+
+	path = strings.ToLower(strings.TrimSuffix(strings.Trim(path, "/"), ".git"))
+	if path == "" {
+		return "", ""
+	}
+
+	switch strings.ToLower(host) {
+	case "github.com":
+		return "ghcr.io", strings.Split(path, "/")[0]
+	case "gitlab.com":
+		// GitLab hosts images below the project path
+		return "registry.gitlab.com", path
+	}
+	return "", ""
+}
+
+// promptInitSettings lets the user confirm or change every detected value. Prompts are written to stderr.
+func promptInitSettings(settings *initSettings) {
+	reader := bufio.NewReader(os.Stdin)
+
+	if len(settings.Images) > 0 {
+		ui.Info("Detected images:")
+		for _, spec := range settings.Images {
+			fmt.Fprintf(ui.Stderr(), "  %s (%s)\n", spec.Name, filepath.Join(spec.Context, stringTernary(spec.Dockerfile == "", "Dockerfile", spec.Dockerfile)))
+		}
+	} else {
+		settings.ImageName = promptValue(reader, "Image name", settings.ImageName)
+	}
+	settings.Registry = promptValue(reader, "Registry", settings.Registry)
+	settings.Namespace = promptValue(reader, "Namespace", settings.Namespace)
+
+	tags := promptValue(reader, "Tags (comma separated)", strings.Join(settings.Tags, ","))
+	settings.Tags = []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			settings.Tags = append(settings.Tags, tag)
+		}
+	}
+	if len(settings.Tags) == 0 {
+		settings.Tags = []string{"latest"}
+	}
+}
+
+// promptValue asks for a value, returning the default value on empty input
+func promptValue(reader *bufio.Reader, label string, defaultValue string) string {
+	fmt.Fprintf(ui.Stderr(), "%s [%s]: ", label, defaultValue)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	if input = strings.TrimSpace(input); input != "" {
+		return input
+	}
+	return defaultValue
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/credstore"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/registry"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login [REGISTRY]",
+	Short: "Log in to a registry",
+	Long: `Verify credentials against the registry and store them in the docker configuration (or the configured credential helper).
+Stored credentials are used automatically when pushing images.
+
+REGISTRY defaults to the configured registry or Docker Hub.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registryName := targetRegistry(args)
+		username := viper.GetString("username")
+		password := viper.GetString("password")
+
+		if username == "" && password == "" {
+			if !term.IsTerminal(os.Stdin.Fd()) {
+				ui.ErrorAndExit(failure.ConfigInvalid.Code(), "No credentials provided. Use --username together with --password-stdin, --password-file or DRAIDE_PASSWORD.")
+			}
+			username, password = promptCredentials()
+			ui.AddSecret(password)
+		}
+
+		client := registry.NewClient(registryName, registry.Credentials{Username: username, Password: password})
+		if err := client.Ping(); err != nil {
+			ui.Fail(err, "Login to %s failed", registryName)
+		}
+
+		if err := credstore.Store(registryName, client.Credentials); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed storing credentials")
+		}
+
+		ui.Success("Login to %s succeeded", registryName)
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout [REGISTRY]",
+	Short: "Log out from a registry",
+	Long: `Remove stored credentials of a registry.
+
+REGISTRY defaults to the configured registry or Docker Hub.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registryName := targetRegistry(args)
+
+		if err := credstore.Erase(registryName); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Logout from %s failed", registryName)
+		}
+
+		ui.Success("Removed credentials for %s", registryName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+}
+
+func targetRegistry(args []string) string {
+	if len(args) > 0 {
+		return registry.NormalizeRegistry(args[0])
+	}
+	return registry.NormalizeRegistry(viper.GetString("registry"))
+}
+
+func promptCredentials() (string, string) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Fprint(ui.Stderr(), "Username: ")
+	username, err := reader.ReadString('\n')
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+
+	fmt.Fprint(ui.Stderr(), "Password: ")
+	state, err := term.SaveState(os.Stdin.Fd())
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	term.DisableEcho(os.Stdin.Fd(), state)
+	password, err := reader.ReadString('\n')
+	term.RestoreTerminal(os.Stdin.Fd(), state)
+	fmt.Fprintln(ui.Stderr())
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+
+	username = strings.TrimSpace(username)
+	password = strings.TrimRight(password, "\r\n")
+	if username == "" || password == "" {
+		ui.ErrorAndExit(1, "Incomplete credentials. Username and password information must be provided.")
+	}
+
+	return username, password
+}
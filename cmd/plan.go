@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/registry"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// addDryRunFlags adds the flags controlling dry-run mode to a command
+func addDryRunFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Print the plan without connecting to Docker or any registry")
+	cmd.Flags().StringP("output", "o", "text", "Format of the dry-run plan: text or json")
+}
+
+// isDryRun reports whether dry-run mode is requested, validating the plan format
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		ui.ErrorAndExit(1, err.Error())
+	}
+	if output != "text" && output != "json" {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unsupported output format: %s", output)
+	}
+	return dryRun
+}
+
+// newPlan creates a plan describing how the configuration was resolved
+func newPlan(command string) *report.Plan {
+	plan := report.NewPlan(command)
+	plan.ConfigFiles = append(plan.ConfigFiles, config.LoadedFiles()...)
+	plan.Presets = append(plan.Presets, config.AppliedPresets()...)
+	plan.MatchedRules = append(plan.MatchedRules, config.MatchedRules()...)
+	return plan
+}
+
+// printPlan prints a plan in the format given by the --output flag
+func printPlan(cmd *cobra.Command, plan *report.Plan) {
+	output, _ := cmd.Flags().GetString("output")
+
+	if output == "json" {
+		content, err := plan.JSON()
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed encoding plan")
+		}
+		fmt.Fprint(ui.Stdout(), content)
+		return
+	}
+	fmt.Fprint(ui.Stdout(), plan.Text())
+}
+
+// planRegistries returns the registries the given images are pushed to together with the credentials used for each
+func planRegistries(images []string) []report.PlannedRegistry {
+	registries := []report.PlannedRegistry{}
+	seen := map[string]bool{}
+
+	for _, image := range images {
+		registryName := registry.ParseReference(image).Registry
+		if seen[registryName] {
+			continue
+		}
+		seen[registryName] = true
+
+		creds, source := registryCredentialSource(registryName)
+		registries = append(registries, report.PlannedRegistry{
+			Registry:         registryName,
+			Authenticated:    creds.Username != "",
+			CredentialSource: source,
+		})
+	}
+
+	return registries
+}
@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/marcelriegr/draide/internal/config"
 	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/failure"
 	"github.com/marcelriegr/draide/pkg/imgtools"
 	"github.com/marcelriegr/draide/pkg/ui"
 
@@ -9,17 +14,40 @@ import (
 	"github.com/spf13/viper"
 )
 
+// pushRetryDelay is the delay before the first retry of a failed push, later retries wait accordingly longer
+const pushRetryDelay = 2 * time.Second
+
 // pushCmd represents the push command
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push an image",
-	Long:  `Description tbd`,
-	Args:  cobra.NoArgs,
+	Long: `Push the image tagged with the configured tags.
+
+With --dry-run the configuration, templates, tags, credentials and rules are resolved as usual, but instead of pushing
+the resulting plan is printed. Neither Docker nor any registry is contacted.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		if rule := config.PushRule(); rule != "" && !viper.GetBool("push") {
+			nothingToDo("push", "Push disabled by rule %s. Nothing to do.", rule)
+			return
+		}
+
 		repositoryFormat := viper.GetString("repository-format")
 		templateVars := parser.GenerateTemplateVars(parser.GenerateTemplateVarsOptions{})
 		tagTemplates := viper.GetStringSlice("tags")
-		tags := parser.RepositoryName(repositoryFormat, tagTemplates, templateVars)
+		tags := repositoryNames(repositoryFormat, tagTemplates, templateVars)
+
+		if isDryRun(cmd) {
+			plan := newPlan("push")
+			plan.AddImage(&report.PlannedImage{
+				Name:       templateVars["IMAGE_NAME"],
+				Tags:       tags,
+				Push:       true,
+				Registries: planRegistries(tags),
+			})
+			printPlan(cmd, plan)
+			return
+		}
 
 		if viper.GetBool("verbose") {
 			ui.Log("Used configuration:")
@@ -33,22 +61,65 @@ var pushCmd = &cobra.Command{
 			}
 		}
 
+		ui.SetImage(templateVars["IMAGE_NAME"])
 		ui.Info("Pushing image...")
 		if len(tags) == 0 {
-			ui.ErrorAndExit(1, "Abort. No valid image tag found.")
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Abort. No valid image tag found.")
 		}
+		rep := newReport("push")
+		image := rep.AddImage(&report.Image{Name: templateVars["IMAGE_NAME"], Tags: tags})
+		ui.StartGroup("Push " + image.Name)
 		for _, repository := range tags {
-			imgtools.Push(repository, imgtools.PushOptions{
-				Auth: imgtools.AuthConfig{
-					Username: viper.GetString("username"),
-					Password: viper.GetString("password"),
-				},
-			})
+			ui.SetTag(repository)
+			result := pushImage(repository)
+			image.Digest = stringTernary(result.Digest == "", image.Digest, result.Digest)
+			image.PushedTags = append(image.PushedTags, repository)
+			recordPushTiming(image, repository, result)
 			ui.Success(" > %s pushed succefully", repository)
 		}
+		ui.EndGroup()
+		image.Pushed = true
+
+		printTimings(rep)
+		writeReport(rep)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(pushCmd)
+	addDryRunFlags(pushCmd)
+}
+
+// pushImage pushes an image with the configured credentials. Failed pushes are retried with increasing delay,
+// unless the Docker daemon is unreachable or the registry rejected the credentials.
+func pushImage(repository string) imgtools.PushResult {
+	start := time.Now()
+	retries := viper.GetInt("pushRetries")
+	for attempt := 1; ; attempt++ {
+		result, err := imgtools.Push(runContext, repository, imgtools.PushOptions{
+			Auth: imgtools.AuthConfig{
+				Username: viper.GetString("username"),
+				Password: viper.GetString("password"),
+			},
+		})
+		if err == nil {
+			// retries count towards the time spent pushing
+			result.Duration = time.Since(start)
+			return result
+		}
+		switch {
+		case failure.ClassOf(err) == failure.Cancelled:
+			ui.Fail(err, "Push of %s cancelled", repository)
+		case failure.ClassOf(err) != failure.PushFailed || attempt > retries:
+			ui.Fail(err, "Failed pushing %s", repository)
+		}
+
+		delay := time.Duration(attempt) * pushRetryDelay
+		ui.Log(err.Error())
+		ui.Warning("Failed pushing %s. Retrying in %s (%d/%d)...", repository, delay, attempt, retries)
+		select {
+		case <-time.After(delay):
+		case <-runContext.Done():
+		}
+	}
 }
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/viper"
+)
+
+var outputNameReplacer = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// runReport is the report of the current run, written on exit if the run fails
+var runReport *report.Report
+
+// newReport creates the report of the current run
+func newReport(command string) *report.Report {
+	runReport = report.New(command)
+	return runReport
+}
+
+// writeExitReport writes the report of a run exiting early, such as on failure, including its exit code and class
+func writeExitReport(command string, code int) {
+	if runReport != nil {
+		printTimings(runReport)
+	}
+	if viper.GetString("reportFile") == "" && viper.GetString("metricsFile") == "" && viper.GetString("junitFile") == "" {
+		return
+	}
+	if runReport == nil {
+		newReport(command)
+	}
+	runReport.SetExitCode(code)
+	writeReport(runReport)
+}
+
+// nothingToDo ends a run which had nothing to do. With --detailed-exit-code it exits with the nothing-to-do exit code.
+func nothingToDo(command string, format string, args ...interface{}) {
+	ui.Success(format, args...)
+	if viper.GetBool("detailedExitCode") {
+		ui.Exit(failure.NothingToDo.Code())
+	}
+	rep := newReport(command)
+	rep.Class = string(failure.NothingToDo)
+	writeReport(rep)
+}
+
+// writeReport stores the report if a report file is configured and publishes the results as CI outputs
+func writeReport(r *report.Report) {
+	writeCIOutputs(r)
+	for _, image := range r.Images {
+		for _, tag := range image.Tags {
+			if image.Built || image.Pushed {
+				ui.Result(resultReference(tag, stringTernary(image.Pushed, image.Digest, "")))
+			}
+		}
+	}
+
+	if path := viper.GetString("metricsFile"); path != "" {
+		if err := r.WriteMetrics(path); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed writing metrics to %s", path)
+		}
+		ui.Log("Metrics written to %s", path)
+	}
+
+	if path := viper.GetString("junitFile"); path != "" {
+		if err := r.WriteJUnit(path); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed writing test results to %s", path)
+		}
+		ui.Log("Test results written to %s", path)
+	}
+
+	path := viper.GetString("reportFile")
+	if path == "" {
+		return
+	}
+
+	if err := r.Write(path); err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed writing report to %s", path)
+	}
+	ui.Log("Report written to %s", path)
+}
+
+// writeCIOutputs appends image IDs, digests and tags to $GITHUB_OUTPUT on GitHub Actions or writes them to a dotenv file
+// on GitLab CI. Values are written per image, prefixed with the image name, and without prefix if the run handled a single image.
+func writeCIOutputs(r *report.Report) {
+	provider := ui.CIProvider()
+	path := viper.GetString("ciOutputFile")
+	flag := os.O_APPEND
+	if path == "" {
+		switch provider {
+		case ui.CIGitHub:
+			path = os.Getenv("GITHUB_OUTPUT")
+		case ui.CIGitLab:
+			path = gitLabOutputFile()
+			flag = os.O_TRUNC
+		}
+	}
+	if path == "" || len(r.Images) == 0 {
+		return
+	}
+
+	var content strings.Builder
+	write := func(prefix string, image *report.Image) {
+		values := [][2]string{
+			{"image_id", image.ImageID},
+			{"digest", image.Digest},
+			{"tags", strings.Join(image.Tags, ",")},
+		}
+		for _, v := range values {
+			key := prefix + v[0]
+			if provider == ui.CIGitLab {
+				key = "DRAIDE_" + strings.ToUpper(key)
+			}
+			fmt.Fprintf(&content, "%s=%s\n", key, v[1])
+		}
+	}
+	for _, image := range r.Images {
+		if image.Name != "" {
+			write(strings.ToLower(outputNameReplacer.ReplaceAllString(image.Name, "_"))+"_", image)
+		}
+	}
+	if len(r.Images) == 1 {
+		write("", r.Images[0])
+	}
+
+	f, err := os.OpenFile(path, flag|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = f.WriteString(content.String())
+		f.Close()
+	}
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed writing CI outputs to %s", path)
+	}
+	ui.Log("CI outputs written to %s", path)
+}
+
+// gitLabOutputFile returns the dotenv file CI outputs are written to on GitLab CI, which is meant to be declared
+// as artifacts:reports:dotenv. It lives in a directory ignoring itself, so that it never shows up as change in the
+// checkout, and is overwritten by each run rather than accumulating keys of earlier runs.
+func gitLabOutputFile() string {
+	dir := filepath.Join(os.Getenv("CI_PROJECT_DIR"), ".draide")
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0644)
+	}
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(1, "Failed creating directory %s for CI outputs", dir)
+	}
+	return filepath.Join(dir, "outputs.env")
+}
+
+// resultReference returns the reference printed as result in quiet mode, pinned to the digest if known
+func resultReference(tag string, digest string) string {
+	if digest == "" {
+		return tag
+	}
+	return tag + "@" + digest
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/marcelriegr/draide/pkg/types"
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/gittools"
 	"github.com/marcelriegr/draide/pkg/ui"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -24,37 +27,132 @@ var rootCmd = &cobra.Command{
 	Long: `Utility tools to build and publish Docker image
 
 Available template variables:
-	$<ENV_VAR>			Environment variable
+	$<ENV_VAR>			Environment variable or variable of an env file (see --env-file)
+	${<ENV_VAR>}			Same as $<ENV_VAR>, required for names containing dots
 	#<ENV_VAR>			Alias for $<ENV_VAR> as the syntax may get evaluated by the system and thus requires escaping to be passed correctly
 	%REGISTRY%			Registry (see --registry flag)
 	%NAMESPACE%			Namespace (see --namespace flag)
 	%IMAGE_NAME%			Image name (see --name flag)
 	%BRANCH%			Git branch name of current directory
 	%COMMIT_HASH%			Git commit hash of current directory
+	%SHORT_COMMIT_HASH%		First 7 characters of the git commit hash of current directory
+	%TAG%				Git tag pointing at the commit of current directory
+	%SEMVER%			Semantic version of the git tag without v prefix, such as 1.2.3 for v1.2.3
+	%CONTEXT_HASH%			Hash of the build context after .dockerignore filtering, the Dockerfile and the build arguments (build and run commands)
+
+Configuration is merged in the following order, later sources take precedence:
+	1. $HOME/.draide.yaml
+	2. .draide.yaml in the root of the git repository
+	3. .draide.yaml of every directory below the git root down to CONTEXT_DIR (build command) or the current directory
+	4. Presets selected via --preset
+	5. Environment variables (DRAIDE_*)
+	6. Flags
+Images declared in the images section additionally apply the .draide.yaml files of their context directory on top of presets.
+Files listed in the include section of a configuration file are merged before the file itself.
+
+Exit codes:
+	0	Success
+	1	General failure
+	2	Invalid configuration, flags or templates
+	3	Docker daemon unreachable
+	4	Build failed
+	5	Registry rejected the credentials
+	6	Push failed after retries
+	7	Nothing to build or push (with --detailed-exit-code only)
+	8	Cancelled by SIGINT or SIGTERM
+	9	A check of the test section failed
+The run command exits with the exit code of the container instead.
 `,
 }
 
 // Execute Cobra
 func Execute() {
+	// help goes to stdout through the writer of the ui package. Errors are printed below instead of by cobra,
+	// which writes them to the same writer as help.
+	rootCmd.SetOut(ui.Stdout())
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintf(ui.Stderr(), "Error: %s\nRun 'draide --help' for usage.\n", ui.Redact(err.Error()))
+		os.Exit(failure.ConfigInvalid.Code())
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		switch format := viper.GetString("logFormat"); format {
+		case "text", "json":
+		default:
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unsupported log format: %s", format)
+		}
+		switch color := viper.GetString("color"); color {
+		case "auto", "always", "never":
+		default:
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unsupported color mode: %s", color)
+		}
+		switch provider := viper.GetString("ci"); provider {
+		case "auto", "none", ui.CIGitHub, ui.CIGitLab:
+		default:
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unsupported CI provider: %s", provider)
+		}
+
+		// only commands talking to Docker or registries cancel gracefully, others such as the prompts of init and login
+		// keep the default handling which exits on the first signal
+		switch cmd {
+		case buildCmd, pushCmd, tagCmd, runCmd:
+			handleSignals()
+		}
+		ui.OnExit(func(code int) {
+			if code == failure.Cancelled.Code() {
+				printCancelSummary()
+			}
+			writeExitReport(cmd.Name(), code)
+		})
+		ui.SetPhase("config")
+		// `init` creates the configuration file and must not fail on an existing one
+		if cmd != initCmd {
+			initConfig(cmd, args)
+		}
+		ui.SetPhase(cmd.Name())
+	}
 
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Logging verbosity")
-	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	config.BindFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only print errors and the final tags or digests, one per line on stdout")
+	config.BindFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+
+	rootCmd.PersistentFlags().String("color", "auto", "Colour diagnostics: auto, always or never. auto colours if stderr is a terminal and NO_COLOR is not set.")
+	config.BindFlag("color", rootCmd.PersistentFlags().Lookup("color"))
+
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format: text or json. JSON emits one event per line to stderr and the final tags or digests to stdout.")
+	config.BindFlag("logFormat", rootCmd.PersistentFlags().Lookup("log-format"))
+
+	rootCmd.PersistentFlags().String("ci", "auto", "Adapt output to a CI provider: auto, github, gitlab or none. auto detects GitHub Actions and GitLab CI.")
+	config.BindFlag("ci", rootCmd.PersistentFlags().Lookup("ci"))
+
+	rootCmd.PersistentFlags().String("ci-output-file", "", "File image IDs, digests and tags are appended to (default $GITHUB_OUTPUT on GitHub Actions, $CI_PROJECT_DIR/.draide/outputs.env on GitLab CI, overwritten per run)")
+	config.BindFlag("ciOutputFile", rootCmd.PersistentFlags().Lookup("ci-output-file"))
+
+	rootCmd.PersistentFlags().String("metrics-file", "", "Write build and push timings to the given file in OpenMetrics text format")
+	config.BindFlag("metricsFile", rootCmd.PersistentFlags().Lookup("metrics-file"))
+
+	rootCmd.PersistentFlags().String("junit-file", "", "Write the results of the checks of the test section to the given file in JUnit XML format")
+	config.BindFlag("junitFile", rootCmd.PersistentFlags().Lookup("junit-file"))
+
+	rootCmd.PersistentFlags().Int("push-retries", 2, "Number of retries if pushing an image fails. Rejected credentials and an unreachable Docker daemon are not retried.")
+	config.BindFlag("pushRetries", rootCmd.PersistentFlags().Lookup("push-retries"))
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default ./.draide.yaml or $HOME/.draide.yaml)")
+	rootCmd.PersistentFlags().Bool("detailed-exit-code", false, "Exit with code 7 instead of 0 if there is nothing to build or push")
+	config.BindFlag("detailedExitCode", rootCmd.PersistentFlags().Lookup("detailed-exit-code"))
 
-	rootCmd.PersistentFlags().StringVarP(&preset, "preset", "p", "", "Use presets")
-	viper.BindPFlag("preset", rootCmd.PersistentFlags().Lookup("preset"))
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: $HOME/.draide.yaml merged with every .draide.yaml from the git root down to CONTEXT_DIR or the current directory)")
+
+	rootCmd.PersistentFlags().StringVarP(&preset, "preset", "p", "", "Use presets. Multiple presets are separated by comma and applied in order.")
+	config.BindFlag("preset", rootCmd.PersistentFlags().Lookup("preset"))
 
 	rootCmd.PersistentFlags().StringVarP(&imageName, "name", "n", "", "Image name. (default <directory-name>)")
-	viper.BindPFlag("imageName", rootCmd.PersistentFlags().Lookup("name"))
+	config.BindFlag("imageName", rootCmd.PersistentFlags().Lookup("name"))
 	cwd, err := os.Getwd()
 	if err != nil {
 		ui.ErrorAndExit(1, err.Error())
@@ -64,133 +162,149 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().StringSliceP("tag", "t", []string{}, "Image tag. Value may contain template variable.")
-	viper.BindPFlag("tags", rootCmd.PersistentFlags().Lookup("tag"))
-	viper.SetDefault("tags", []string{"latest"})
+	config.BindFlag("tags", rootCmd.PersistentFlags().Lookup("tag"))
+	config.SetDefault("tags", []string{"latest"})
 
 	rootCmd.PersistentFlags().StringP("registry", "r", "", "Container registry, such as: k8s.gcr.io")
-	viper.BindPFlag("registry", rootCmd.PersistentFlags().Lookup("registry"))
+	config.BindFlag("registry", rootCmd.PersistentFlags().Lookup("registry"))
 
 	rootCmd.PersistentFlags().String("namespace", "", "Repository namespace")
-	viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
+	config.BindFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
 
 	rootCmd.PersistentFlags().String("repository-format", "%REGISTRY%/%NAMESPACE%/%IMAGE_NAME%", "Format to construct repository name. Value may contain template variable.")
-	viper.BindPFlag("repository-format", rootCmd.PersistentFlags().Lookup("repository-format"))
+	config.BindFlag("repository-format", rootCmd.PersistentFlags().Lookup("repository-format"))
+
+	rootCmd.PersistentFlags().StringSlice("env-file", []string{}, "Read variables for $VAR interpolation from .env files. Later files override earlier ones.")
+	config.BindFlag("envFiles", rootCmd.PersistentFlags().Lookup("env-file"))
+	config.SetDefault("envFilePrecedence", "environment")
+
+	rootCmd.PersistentFlags().String("report-file", "", "Write a JSON report of the run to the given file")
+	config.BindFlag("reportFile", rootCmd.PersistentFlags().Lookup("report-file"))
 
 	rootCmd.PersistentFlags().String("username", "", "Username for pushing image into registry")
-	viper.BindPFlag("username", rootCmd.PersistentFlags().Lookup("username"))
+	config.BindFlag("username", rootCmd.PersistentFlags().Lookup("username"))
+
+	config.BindEnv("username", "DRAIDE_USERNAME")
 
-	rootCmd.PersistentFlags().String("password", "", "Password for pushing image into registry")
-	rootCmd.PersistentFlags().Bool("password-stdin", false, "Password for pushing image into registry via stdin. Password supplied via stdin will take precedence over the --password flag.")
-	viper.BindPFlag("password", rootCmd.PersistentFlags().Lookup("password"))
+	rootCmd.PersistentFlags().String("password", "", "Password for pushing image into registry. Prefer --password-file, --password-stdin or DRAIDE_PASSWORD as flag values are visible in the process list.")
+	rootCmd.PersistentFlags().Bool("password-stdin", false, "Password for pushing image into registry via stdin. Password supplied via stdin will take precedence over any other source.")
+	rootCmd.PersistentFlags().String("password-file", "", "Path to a file containing the password for pushing image into registry. Takes precedence over --password and DRAIDE_PASSWORD.")
+	config.BindFlag("password", rootCmd.PersistentFlags().Lookup("password"))
+	config.BindEnv("password", "DRAIDE_PASSWORD")
 }
 
-func initConfig() {
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
+func initConfig(cmd *cobra.Command, args []string) {
+	viper.SetEnvPrefix("draide")
+	viper.AutomaticEnv()
+
+	config.RecordDefaults()
+
+	files := []string{cfgFile}
+	if cfgFile == "" {
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			ui.ErrorAndExit(1, err.Error())
 		}
 
-		viper.SetConfigName(".draide")
-		viper.AddConfigPath(".")
-		viper.AddConfigPath(home)
+		files, err = config.DiscoverFiles(configDir(cmd, args), home)
+		if err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(1, "Failed searching configuration files")
+		}
 	}
 
-	viper.SetEnvPrefix("draide")
-	viper.AutomaticEnv()
-
-	switch err := viper.ReadInConfig(); err.(type) {
+	fragments, err := config.ResolveFiles(files)
+	switch err := err.(type) {
 	case nil:
-		ui.Info("Using configuration file: %s", viper.ConfigFileUsed())
-
-		if preset != "" {
-			presetsKey := "presets." + preset
-			if !viper.IsSet(presetsKey) {
-				ui.ErrorAndExit(1, "Unable to find preset configuration for: %s", preset)
-			}
-
-			presetSettings := viper.Sub(presetsKey)
-			err = viper.MergeConfigMap(viper.Sub(presetsKey).AllSettings())
-			if err != nil {
-				ui.Log(err.Error())
-				ui.ErrorAndExit(1, "Failed parsing preset configuration")
-			}
-
-			if presetSettings.IsSet("extraBuildArgs") {
-				var buildArgs, buildArgsOfPreset []types.KeyValueConfig
-
-				// unmarshal values into an interface as a workaround to enable case-sensitive data loading from config file
-				// ref: https://github.com/spf13/viper/issues/373
-				err = viper.UnmarshalKey("buildArgs", &buildArgs)
-				if err != nil {
-					ui.Log(err.Error())
-					ui.ErrorAndExit(1, "Failed parsing build arguments from configuration file")
-				}
-				err = presetSettings.UnmarshalKey("extraBuildArgs", &buildArgsOfPreset)
-				if err != nil {
-					ui.Log(err.Error())
-					ui.ErrorAndExit(1, "Failed parsing preset's extra build arguments from configuration file")
-				}
-				viper.Set("buildArgs", append(buildArgs, buildArgsOfPreset...))
-			}
+	case *config.ParseError:
+		ui.Log(err.Error())
+		validateConfig(err.Source, err.Content, false)
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Error while parsing configuration file %s", err.Source)
+	default:
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), err.Error())
+	}
 
-			if presetSettings.IsSet("extraTags") {
-				tags := viper.GetStringSlice("tags")
-				extraTags := viper.GetStringSlice("extraTags")
-				viper.Set("tags", append(tags, extraTags...))
-			}
-		}
-	case viper.ConfigFileNotFoundError:
+	if len(fragments) == 0 {
 		ui.Log("Proceed without configuration file")
-	case viper.ConfigParseError:
-		ui.ErrorAndExit(1, "Error while parsing configuration file")
-	default:
-		ui.ErrorAndExit(1, err.Error())
 	}
+	for _, f := range fragments {
+		ui.Info("Using configuration file: %s%s", f.Source, stringTernary(f.IncludedBy == "", "", " (included from "+f.IncludedBy+")"))
 
-	initCredentials()
-}
+		// `config validate` reports issues itself
+		if cmd != configValidateCmd && !validateConfig(f.Source, f.Content, false) {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid configuration file")
+		}
+	}
+	if err := config.LoadFragments(fragments); err != nil {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), err.Error())
+	}
 
-func initCredentials() {
-	// read credentials from stdin
-	passwordStdIn, err := rootCmd.PersistentFlags().GetBool("password-stdin")
-	if err != nil {
-		ui.ErrorAndExit(1, err.Error())
+	if cmd == tagCmd {
+		if err := captureSourceSettings(); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed resolving the configuration of the source image")
+		}
 	}
 
-	if passwordStdIn {
-		fi, err := os.Stdin.Stat()
+	if preset != "" {
+		err = config.ApplyPresets(config.ParsePresetNames(preset))
 		if err != nil {
-			ui.ErrorAndExit(1, err.Error())
-		} else if fi.Mode()&os.ModeNamedPipe == 0 {
-			ui.ErrorAndExit(1, "No password from stdin received")
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed applying preset configuration %s: %v", preset, err)
 		}
+		ui.Log("Applied presets: %s", strings.Join(config.AppliedPresets(), ", "))
+	}
 
-		scanner := bufio.NewScanner(bufio.NewReader(os.Stdin))
-		scanner.Scan()
-		password := scanner.Text()
+	config.RecordOverrides()
+	initCredentials()
+	initEnvFiles()
+	initRules(cmd, args)
+}
 
-		if password == "" {
-			ui.ErrorAndExit(1, "Found empty string as password from stdin")
+// configDir returns the directory configuration files are searched from: the CONTEXT_DIR of the build command or the current directory
+func configDir(cmd *cobra.Command, args []string) string {
+	if cmd == buildCmd && len(args) > 0 {
+		contextDir, err := homedir.Expand(args[0])
+		if err == nil {
+			return contextDir
 		}
+	}
+	return "."
+}
+
+func initEnvFiles() {
+	switch precedence := viper.GetString("envFilePrecedence"); precedence {
+	case "environment", "file":
+	default:
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid envFilePrecedence %q. Supported values are environment and file.", precedence)
+	}
 
-		viper.Set("password", password)
-		ui.Log("Using password from stdin")
+	if err := parser.LoadEnvFiles(viper.GetStringSlice("envFiles")); err != nil {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed reading env files: %s", err.Error())
 	}
+}
 
-	// check credentials completeness
-	username := viper.GetString("username")
-	password := viper.GetString("password")
-	if (username == "" && password != "") || (username != "" && password == "") {
-		ui.ErrorAndExit(1, "Incomplete credentials. Username and password information must be provided.")
+func initRules(cmd *cobra.Command, args []string) {
+	if !viper.IsSet("rules") {
+		return
 	}
 
-	if ui.IsVerbose() && username != "" && password != "" {
-		ui.Log("Credentials:")
-		ui.Log(" > username: %s", username)
-		ui.Log(" > password: ******")
+	contextDir := configDir(cmd, args)
+	templateVars := parser.GenerateTemplateVars(parser.GenerateTemplateVarsOptions{ContextDir: contextDir})
+	err := config.ApplyRules(config.RuleContext{
+		Branch: templateVars["BRANCH"],
+		Tag:    templateVars["TAG"],
+		Dirty: func() bool {
+			dirty, err := gittools.IsDirty(contextDir)
+			if err != nil {
+				ui.Log("Failed reading git status: %s", err.Error())
+			}
+			return dirty
+		},
+		LookupEnv: parser.LookupEnv,
+	})
+	if err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed applying rules")
 	}
+	ui.Log("Matched rules: %s", stringTernary(len(config.MatchedRules()) == 0, "<none>", strings.Join(config.MatchedRules(), ", ")))
 }
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/imgtools"
+	"github.com/marcelriegr/draide/pkg/types"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [IMAGE] [-- CMD...]",
+	Short: "Run the image",
+	Long: `Start a container of the image tagged with the first of the configured tags, such as after draide build.
+
+IMAGE names one of the images declared in the images section of the configuration file. It may be omitted if only one
+image is declared. Without images section, the image of the current directory is run. Tags are resolved like by the
+build command, including %CONTEXT_HASH%.
+
+Ports, environment variables, volumes and the network are taken from the run section of the configuration file
+and the corresponding flags. CMD overrides the command of the image.
+The output of the container is streamed until it exits, then the container is removed and draide exits with its exit code.
+A non-zero exit code of the container is reported with class container-exited rather than the class of the code.
+SIGINT and SIGTERM are forwarded to the container, a second signal kills it.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if names, _ := splitRunArgs(cmd, args); len(names) > 1 {
+			return fmt.Errorf("accepts at most 1 IMAGE, received %d. Pass the command of the container after --", len(names))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		names, containerCmd := splitRunArgs(cmd, args)
+		spec := runImageSpec(names)
+		applyImageConfig(spec)
+
+		templateVars := imageTemplateVars(spec)
+		resolveBuildArgs(cmd, templateVars, spec.Context, imageDockerfileName(spec, templateVars))
+		tags := repositoryNames(viper.GetString("repository-format"), viper.GetStringSlice("tags"), templateVars)
+		if len(tags) == 0 {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Abort. No valid image tag found.")
+		}
+		image := tags[0]
+
+		var envFromConfig []types.KeyValueConfig
+		// unmarshal values into a list as a workaround to enable case-sensitive data loading from config file
+		if err := viper.UnmarshalKey("run.env", &envFromConfig); err != nil {
+			ui.Log(err.Error())
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing environment variables from configuration file")
+		}
+		env := []string{}
+		for _, v := range envFromConfig {
+			value := renderTemplate(v.Value, templateVars)
+			if parser.IsSensitiveKey(v.Key) {
+				ui.AddSecret(value)
+			}
+			env = append(env, v.Key+"="+value)
+		}
+		envFromFlags, err := cmd.Flags().GetStringSlice("env")
+		if err != nil {
+			ui.ErrorAndExit(1, err.Error())
+		}
+		env = append(env, envFromFlags...)
+
+		opts := imgtools.RunOptions{
+			Cmd:     containerCmd,
+			Ports:   viper.GetStringSlice("run.ports"),
+			Env:     env,
+			Volumes: viper.GetStringSlice("run.volumes"),
+			Network: viper.GetString("run.network"),
+		}
+		if viper.GetBool("verbose") {
+			ui.Log("Used configuration:")
+			ui.Log("> image: %s", image)
+			ui.Log("> ports:%s", stringTernary(len(opts.Ports) == 0, " <none>", ""))
+			for _, v := range opts.Ports {
+				ui.Log("  - %s", v)
+			}
+			ui.Log("> volumes:%s", stringTernary(len(opts.Volumes) == 0, " <none>", ""))
+			for _, v := range opts.Volumes {
+				ui.Log("  - %s", v)
+			}
+			ui.Log("> network: %s", stringTernary(opts.Network == "", "<default>", opts.Network))
+		}
+
+		ui.SetImage(image)
+		ui.Info("Running image %s...", image)
+		opts.Signals = forwardSignals()
+		code, err := imgtools.Run(runContext, image, opts)
+		if err != nil {
+			ui.Fail(err, "Failed running image %s", image)
+		}
+		if code != 0 {
+			ui.Error("Container exited with code %d", code)
+			exitWithContainerCode(code)
+		}
+		ui.Success("Container exited with code 0")
+	},
+}
+
+// splitRunArgs splits the arguments of run into the image name and the command of the container following --
+func splitRunArgs(cmd *cobra.Command, args []string) ([]string, []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+	return args[:dash], args[dash:]
+}
+
+// runImageSpec returns the image to run: the declared image of the given name, the only declared image,
+// or the image of the current directory if no images are declared
+func runImageSpec(names []string) imageSpec {
+	if !viper.IsSet("images") {
+		if len(names) > 0 {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unknown image %s. No images are declared in the configuration file.", names[0])
+		}
+		return resolveImageSpecs([]string{"."})[0]
+	}
+
+	specs := resolveImageSpecs(nil)
+	declared := make([]string, len(specs))
+	for i, spec := range specs {
+		if len(names) > 0 && spec.Name == names[0] {
+			return spec
+		}
+		declared[i] = spec.Name
+	}
+	if len(names) > 0 {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unknown image %s. Declared images: %s", names[0], strings.Join(declared, ", "))
+	}
+	if len(specs) != 1 {
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Multiple images are declared. Pass the one to run: %s", strings.Join(declared, ", "))
+	}
+	return specs[0]
+}
+
+// exitWithContainerCode exits with the exit code of a container. The code is reported with class container-exited,
+// as it is the container's code rather than one of draide's failure classes.
+func exitWithContainerCode(code int) {
+	rep := newReport("run")
+	rep.ExitCode = code
+	rep.Class = string(failure.ContainerExited)
+	writeReport(rep)
+	os.Exit(code)
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringSlice("publish", []string{}, "Publish a container port, such as 8080:80")
+	runCmd.Flags().StringSlice("env", []string{}, "Set an environment variable as KEY=VALUE, in addition to those of the configuration file")
+	runCmd.Flags().StringSlice("volume", []string{}, "Bind mount a host path or named volume, such as ./data:/data")
+	runCmd.Flags().String("network", "", "Connect the container to a network")
+
+	config.BindFlag("run.ports", runCmd.Flags().Lookup("publish"))
+	config.BindFlag("run.volumes", runCmd.Flags().Lookup("volume"))
+	config.BindFlag("run.network", runCmd.Flags().Lookup("network"))
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/marcelriegr/draide/internal/config"
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/imgtools"
+	"github.com/marcelriegr/draide/pkg/registry"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag SOURCE",
+	Short: "Retag and promote an existing image without rebuilding",
+	Long: `Apply the configured repository name format and tags to an existing image.
+
+SOURCE is either a full image reference, such as registry.example.com/staging/app:1.2.3, or a tag which is resolved against
+the configuration without the presets given by --preset, which describe the targets. Presets describing the source are selected via --source-preset.
+If SOURCE exists in a registry, the image is copied blob by blob to every target repository, so its digest stays identical.
+Otherwise SOURCE is looked up in the local Docker engine, tagged and pushed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repositoryFormat := viper.GetString("repository-format")
+		templateVars := parser.GenerateTemplateVars(parser.GenerateTemplateVarsOptions{})
+		tagTemplates := viper.GetStringSlice("tags")
+		tags := repositoryNames(repositoryFormat, tagTemplates, templateVars)
+
+		noPush, err := cmd.Flags().GetBool("no-push")
+		if err != nil {
+			ui.ErrorAndExit(1, err.Error())
+		}
+
+		source := args[0]
+		if !strings.ContainsAny(source, "/:@") {
+			sourceTemplateVars := parser.TemplateVars{}
+			for k, v := range templateVars {
+				sourceTemplateVars[k] = v
+			}
+			sourceTemplateVars["IMAGE_NAME"] = sourceSettings["imagename"]
+			sourceTemplateVars["REGISTRY"] = sourceSettings["registry"]
+			sourceTemplateVars["NAMESPACE"] = sourceSettings["namespace"]
+			source = repositoryNames(sourceSettings["repository-format"], []string{source}, sourceTemplateVars)[0]
+		}
+
+		if viper.GetBool("verbose") {
+			ui.Log("Used configuration:")
+			ui.Log("> source: %s", source)
+			ui.Log("> source repository name format: %s", sourceSettings["repository-format"])
+			ui.Log("> repository name format: %s", repositoryFormat)
+			ui.Log("> registry: %s", stringTernary(templateVars["REGISTRY"] == "", "<none>", templateVars["REGISTRY"]))
+			ui.Log("> namespace: %s", stringTernary(templateVars["NAMESPACE"] == "", "<none>", templateVars["NAMESPACE"]))
+			ui.Log("> base image name: %s", templateVars["IMAGE_NAME"])
+			ui.Log("> tags:%s", stringTernary(len(tags) == 0, " <none>", ""))
+			for _, v := range tags {
+				ui.Log("  - %s", v)
+			}
+		}
+
+		if len(tags) == 0 {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Abort. No valid image tag found.")
+		}
+
+		srcRef := registry.ParseReference(source)
+		src := registry.NewClient(srcRef.Registry, sourceCredentials(srcRef.Registry))
+		_, err = src.HeadManifest(srcRef.Repository, srcRef.Ref())
+		switch err {
+		case nil:
+			ui.Info("Copying image %s...", srcRef)
+			copyImage(src, srcRef, tags)
+			return
+		case registry.ErrNotFound:
+			ui.Log("Image %s not available in registry", srcRef)
+		default:
+			// falling back to a local image of the same name could promote something else than the requested image
+			ui.Fail(err, "Failed looking up image %s in registry", srcRef)
+		}
+
+		exists, err := imgtools.Exists(runContext, source)
+		if err != nil {
+			ui.Fail(err, "Failed establishing connection to Docker engine")
+		}
+		if !exists {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Unable to find image %s neither in a registry nor locally", source)
+		}
+
+		ui.Info("Tagging local image %s...", source)
+		for _, repository := range tags {
+			if err := imgtools.Tag(runContext, source, repository); err != nil {
+				ui.Fail(err, "Failed tagging image %s as %s", source, repository)
+			}
+			ui.Success(" > %s tagged successfully", repository)
+			if noPush {
+				ui.Result(repository)
+			}
+		}
+
+		if !noPush {
+			ui.Info("Pushing image...")
+			for _, repository := range tags {
+				result := pushImage(repository)
+				ui.Success(" > %s pushed successfully", repository)
+				ui.Result(resultReference(repository, result.Digest))
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+
+	tagCmd.Flags().Bool("no-push", false, "Only tag a local image without pushing it")
+	tagCmd.Flags().String("source-preset", "", "Comma separated presets a SOURCE tag is resolved with, such as the preset of the registry an image is promoted from")
+}
+
+// sourceSettingKeys are the settings a SOURCE tag is resolved with
+var sourceSettingKeys = []string{"repository-format", "registry", "namespace", "imagename"}
+
+// sourceSettings holds the settings a SOURCE tag is resolved with, captured before the presets describing the targets are applied
+var sourceSettings = map[string]string{}
+
+// captureSourceSettings records the settings SOURCE is resolved with: the configuration files and flags, overridden by the presets given by --source-preset
+func captureSourceSettings() error {
+	for _, key := range sourceSettingKeys {
+		sourceSettings[key] = viper.GetString(key)
+	}
+
+	names, err := tagCmd.Flags().GetString("source-preset")
+	if err != nil {
+		return err
+	}
+	presets, err := config.ResolvePresets(config.ParsePresetNames(names))
+	if err != nil {
+		return err
+	}
+	for _, name := range presets {
+		for _, key := range sourceSettingKeys {
+			if presetKey := "presets." + name + "." + key; viper.IsSet(presetKey) {
+				sourceSettings[key] = viper.GetString(presetKey)
+			}
+		}
+	}
+	return nil
+}
+
+// copyImage copies an image from a registry to all target repositories
+func copyImage(src *registry.Client, srcRef registry.Reference, targets []string) {
+	clients := map[string]*registry.Client{}
+
+	for _, target := range targets {
+		dstRef := registry.ParseReference(target)
+		if dstRef == srcRef {
+			ui.Log("Skipping %s as it is identical to the source", target)
+			continue
+		}
+
+		dst, ok := clients[dstRef.Registry]
+		if !ok {
+			dst = registry.NewClient(dstRef.Registry, registryCredentials(dstRef.Registry))
+			clients[dstRef.Registry] = dst
+		}
+
+		digest, err := registry.Copy(src, srcRef, dst, dstRef)
+		if err != nil {
+			ui.Fail(failure.Wrap(failure.PushFailed, err), "Failed copying image to %s", target)
+		}
+		ui.Success(" > %s copied successfully (%s)", target, digest)
+		ui.Result(resultReference(target, digest))
+	}
+}
+
+// sourceCredentials returns the credentials to read an image from a registry.
+// Explicitly supplied credentials only apply to the configured registry.
+func sourceCredentials(registryName string) registry.Credentials {
+	if registry.NormalizeRegistry(viper.GetString("registry")) == registryName {
+		return registryCredentials(registryName)
+	}
+	return storedCredentials(registryName)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/marcelriegr/draide/internal/parser"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+// renderTemplate resolves environment and template variables of a configuration value, exiting if one cannot be resolved
+func renderTemplate(template string, templateVars parser.TemplateVars) string {
+	value, err := parser.Template(template, templateVars)
+	exitOnError(err)
+	return value
+}
+
+// repositoryNames resolves the repository format together with each tag template, exiting if a template cannot be resolved
+func repositoryNames(repositoryFormat string, tagTemplates []string, templateVars parser.TemplateVars) []string {
+	names, err := parser.RepositoryName(repositoryFormat, tagTemplates, templateVars)
+	exitOnError(err)
+	return names
+}
+
+// exitOnError prints an error and exits with the code of its failure class. It is meant for errors whose message
+// tells the user what to fix, such as configuration errors.
+func exitOnError(err error) {
+	if err != nil {
+		ui.ErrorAndExit(failure.ClassOf(err).Code(), "%s", err.Error())
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/imgtools"
+	"github.com/marcelriegr/draide/pkg/types"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/spf13/viper"
+)
+
+// testChecks loads the checks of the test section. Invalid checks abort the run before anything is built.
+func testChecks() []types.TestCheck {
+	if viper.GetBool("skipTests") {
+		return nil
+	}
+
+	var checks []types.TestCheck
+	if err := viper.UnmarshalKey("test", &checks); err != nil {
+		ui.Log(err.Error())
+		ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Failed parsing checks from the test section of the configuration file")
+	}
+	for i, check := range checks {
+		kinds := testCheckKinds(check)
+		if len(kinds) != 1 {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Check %d of the test section must set exactly one of command, file, exposedPorts, nonRoot or labels", i+1)
+		}
+		if _, err := regexp.Compile(check.Stdout); err != nil {
+			ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid stdout pattern of check %s: %s", testCheckName(check), err.Error())
+		}
+		for _, port := range check.ExposedPorts {
+			if _, err := exposedPort(port); err != nil {
+				ui.ErrorAndExit(failure.ConfigInvalid.Code(), "Invalid port of check %s: %s", testCheckName(check), err.Error())
+			}
+		}
+	}
+	return checks
+}
+
+// testCheckNames returns the names of checks as listed in dry-run plans
+func testCheckNames(checks []types.TestCheck) []string {
+	names := make([]string, len(checks))
+	for i, check := range checks {
+		names[i] = testCheckName(check)
+	}
+	return names
+}
+
+// testImage runs checks against a built image and records their results in the report.
+// It returns whether all checks passed.
+func testImage(image *report.Image, imageName string, checks []types.TestCheck) bool {
+	passed := true
+	for _, check := range checks {
+		checkCancelled()
+		start := time.Now()
+		result := runTestCheck(imageName, check)
+		result.Seconds = time.Since(start).Seconds()
+		image.Tests = append(image.Tests, result)
+
+		if result.Passed {
+			ui.Success(" > %s passed", result.Name)
+			continue
+		}
+		passed = false
+		ui.Error(" > %s failed: %s", result.Name, result.Message)
+		if result.Output != "" {
+			ui.Log("Output of %s:\n%s", result.Name, strings.TrimRight(result.Output, "\n"))
+		}
+	}
+	return passed
+}
+
+// runTestCheck runs a single check. Failures of the check itself are reported in the result,
+// while a cancelled run or an unreachable daemon ends the run.
+func runTestCheck(imageName string, check types.TestCheck) *report.TestResult {
+	kind := testCheckKinds(check)[0]
+	result := &report.TestResult{Name: testCheckName(check), Kind: kind}
+	fail := func(err error) *report.TestResult {
+		class := failure.ClassOf(err)
+		if class == failure.Cancelled || class == failure.DaemonUnreachable {
+			ui.Fail(err, "Failed testing image %s", imageName)
+		}
+		result.Message = err.Error()
+		return result
+	}
+
+	switch kind {
+	case "command":
+		var stdout, stderr bytes.Buffer
+		code, err := imgtools.Run(runContext, imageName, imgtools.RunOptions{
+			Entrypoint: check.Command[:1],
+			Cmd:        check.Command[1:],
+			Stdout:     &stdout,
+			Stderr:     &stderr,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		result.Output = stdout.String() + stderr.String()
+		switch {
+		case code != check.ExitCode:
+			result.Message = fmt.Sprintf("exited with code %d, expected %d", code, check.ExitCode)
+		case !regexp.MustCompile(check.Stdout).MatchString(stdout.String()):
+			result.Message = fmt.Sprintf("stdout does not match %s", check.Stdout)
+		default:
+			result.Passed = true
+		}
+
+	case "file":
+		exists, err := imgtools.FileExists(runContext, imageName, check.File)
+		if err != nil {
+			return fail(err)
+		}
+		result.Passed = exists
+		if !exists {
+			result.Message = fmt.Sprintf("%s does not exist", check.File)
+		}
+
+	default:
+		config, err := imgtools.ImageConfig(runContext, imageName)
+		if err != nil {
+			return fail(err)
+		}
+		switch kind {
+		case "exposedPorts":
+			var missing []string
+			for _, v := range check.ExposedPorts {
+				port, _ := exposedPort(v)
+				if _, ok := config.ExposedPorts[port]; !ok {
+					missing = append(missing, string(port))
+				}
+			}
+			result.Passed = len(missing) == 0
+			if !result.Passed {
+				result.Message = "ports not exposed: " + strings.Join(missing, ", ")
+			}
+		case "nonRoot":
+			result.Passed = !isRootUser(config.User)
+			if !result.Passed {
+				result.Message = stringTernary(config.User == "", "no user set, runs as root", "runs as root user "+config.User)
+			}
+		case "labels":
+			var missing []string
+			for _, label := range check.Labels {
+				if _, ok := config.Labels[label]; !ok {
+					missing = append(missing, label)
+				}
+			}
+			result.Passed = len(missing) == 0
+			if !result.Passed {
+				result.Message = "labels missing: " + strings.Join(missing, ", ")
+			}
+		}
+	}
+
+	return result
+}
+
+// testCheckKinds returns the kinds of checks set, which is exactly one for valid checks
+func testCheckKinds(check types.TestCheck) []string {
+	kinds := []string{}
+	if len(check.Command) > 0 {
+		kinds = append(kinds, "command")
+	}
+	if check.File != "" {
+		kinds = append(kinds, "file")
+	}
+	if len(check.ExposedPorts) > 0 {
+		kinds = append(kinds, "exposedPorts")
+	}
+	if check.NonRoot {
+		kinds = append(kinds, "nonRoot")
+	}
+	if len(check.Labels) > 0 {
+		kinds = append(kinds, "labels")
+	}
+	return kinds
+}
+
+// testCheckName returns the configured name of a check, or describes the check if it has none
+func testCheckName(check types.TestCheck) string {
+	switch {
+	case check.Name != "":
+		return check.Name
+	case len(check.Command) > 0:
+		return "command " + strings.Join(check.Command, " ")
+	case check.File != "":
+		return "file " + check.File
+	case len(check.ExposedPorts) > 0:
+		return "exposed ports " + strings.Join(check.ExposedPorts, ", ")
+	case check.NonRoot:
+		return "non-root user"
+	default:
+		return "labels " + strings.Join(check.Labels, ", ")
+	}
+}
+
+// exposedPort normalizes a port such as 8080 or 53/udp, defaulting to tcp like the EXPOSE instruction
+func exposedPort(value string) (nat.Port, error) {
+	proto, port := nat.SplitProtoPort(value)
+	if _, err := nat.ParsePort(port); err != nil || port == "" {
+		return "", fmt.Errorf("invalid port %s", value)
+	}
+	return nat.NewPort(proto, port)
+}
+
+// isRootUser reports whether the USER of an image refers to root, which is the default if none is set
+func isRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "" || name == "root" || name == "0"
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelriegr/draide/internal/report"
+	"github.com/marcelriegr/draide/pkg/imgtools"
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+// maxInstructionWidth limits the width of build instructions in the timings table
+const maxInstructionWidth = 50
+
+// recordBuildTimings adds the timings of a build to the report
+func recordBuildTimings(image *report.Image, result imgtools.BuildResult) {
+	if image.Timings == nil {
+		image.Timings = &report.Timings{}
+	}
+	image.Timings.ContextSize = result.ContextSize
+	image.Timings.PackingSeconds = result.Packing.Seconds()
+	image.Timings.UploadSeconds = result.Upload.Seconds()
+	image.Timings.BuildSeconds = result.Duration.Seconds()
+	for _, step := range result.Steps {
+		image.Steps = append(image.Steps, &report.Step{
+			Number:      step.Number,
+			Instruction: step.Instruction,
+			Seconds:     step.Duration.Seconds(),
+			Cached:      step.Cached,
+			LayerID:     step.LayerID,
+		})
+	}
+	if len(image.Steps) > 0 {
+		image.Cache = report.NewCacheStats(image.Steps)
+	}
+}
+
+// printCacheSummary prints how many build steps of an image were served from the build cache
+func printCacheSummary(image *report.Image) {
+	stats := image.Cache
+	if stats == nil || stats.CacheableSteps == 0 {
+		return
+	}
+
+	summary := fmt.Sprintf("Build cache: %d of %d steps cached (%.0f%%)", stats.CachedSteps, stats.CacheableSteps, stats.Ratio()*100)
+	if stats.FirstMiss != nil {
+		summary += fmt.Sprintf(". Cache busted at step %d: %s", stats.FirstMiss.Number, truncate(stats.FirstMiss.Instruction, maxInstructionWidth))
+	}
+	ui.Info("%s", summary)
+}
+
+// recordPushTiming adds the time spent pushing a tag to the report
+func recordPushTiming(image *report.Image, tag string, result imgtools.PushResult) {
+	if image.Timings == nil {
+		image.Timings = &report.Timings{}
+	}
+	image.Timings.Pushes = append(image.Timings.Pushes, &report.PushedTag{Tag: tag, Seconds: result.Duration.Seconds()})
+}
+
+// printTimings prints a table of the time spent per image, phase, build step and pushed tag
+func printTimings(r *report.Report) {
+	if ui.IsQuiet() {
+		return
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	rows := 0
+	row := func(image string, phase string, seconds float64) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", image, phase, formatSeconds(seconds))
+		rows++
+	}
+	fmt.Fprintln(w, "IMAGE\tPHASE\tDURATION")
+	for _, image := range r.Images {
+		t := image.Timings
+		if t == nil {
+			continue
+		}
+		if t.ContextSize > 0 {
+			row(image.Name, fmt.Sprintf("context packing (%s)", formatBytes(t.ContextSize)), t.PackingSeconds)
+			row(image.Name, "context upload", t.UploadSeconds)
+		}
+		for _, step := range image.Steps {
+			row(image.Name, fmt.Sprintf("step %d: %s%s", step.Number, truncate(step.Instruction, maxInstructionWidth), stringTernary(step.Cached, " (cached)", "")), step.Seconds)
+		}
+		if t.BuildSeconds > 0 {
+			row(image.Name, "build total", t.BuildSeconds)
+		}
+		for _, push := range t.Pushes {
+			row(image.Name, "push "+push.Tag, push.Seconds)
+		}
+	}
+	w.Flush()
+	if rows == 0 {
+		return
+	}
+
+	ui.Info("Timings:")
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		ui.Info("  %s", line)
+	}
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func truncate(value string, width int) string {
+	if len(value) <= width {
+		return value
+	}
+	return value[:width-3] + "..."
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/gittools"
+
+	"github.com/spf13/viper"
+)
+
+// FileName is the base name of configuration files, the extension is any format supported by viper
+const FileName = ".draide"
+
+var loadedFragments = []Fragment{}
+
+// DiscoverFiles returns the configuration files applying to dir in merge order, later files taking precedence:
+//
+//  1. the user-level file in home (skipped if home is empty)
+//  2. the file in the root of the git repository containing dir
+//  3. the files of every directory below the root down to dir itself
+//
+// Outside a git repository only dir itself is searched besides home.
+func DiscoverFiles(dir string, home string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{dir}
+	if root, err := gittools.GetRootDir(dir); err == nil {
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			for d := dir; d != root; d = filepath.Dir(d) {
+				dirs = append([]string{filepath.Dir(d)}, dirs...)
+			}
+		}
+	}
+	if home != "" {
+		dirs = append([]string{home}, dirs...)
+	}
+
+	files := []string{}
+	seen := map[string]bool{}
+	for _, d := range dirs {
+		if file := findFile(d); file != "" && !seen[file] {
+			files = append(files, file)
+			seen[file] = true
+		}
+	}
+
+	return files, nil
+}
+
+// findFile returns the configuration file of a directory, if any
+func findFile(dir string) string {
+	for _, ext := range viper.SupportedExts {
+		path := filepath.Join(dir, FileName+"."+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadFragments merges configuration fragments into the configuration in order.
+// Nested settings such as labels are merged key by key, all other settings are replaced.
+func LoadFragments(fragments []Fragment) error {
+	for _, f := range fragments {
+		if err := viper.MergeConfigMap(f.Settings); err != nil {
+			return fmt.Errorf("failed merging %s: %v", f.Source, err)
+		}
+		RecordLayer("config file "+f.Source, "set", f.Settings)
+		loadedFragments = append(loadedFragments, f)
+	}
+
+	return nil
+}
+
+// LoadedFragments returns the fragments merged by LoadFragments in merge order
+func LoadedFragments() []Fragment {
+	return loadedFragments
+}
+
+// SettingDir returns the directory relative paths of a top-level setting are resolved against, which is the directory
+// of the last loaded fragment setting it. It returns an empty string if no fragment sets it, e.g. if it is only given via flag.
+func SettingDir(key string) string {
+	for i := len(loadedFragments) - 1; i >= 0; i-- {
+		for k := range loadedFragments[i].Settings {
+			if strings.EqualFold(k, key) {
+				return loadedFragments[i].Dir
+			}
+		}
+	}
+	return ""
+}
+
+// LoadedFiles returns the sources of the fragments merged by LoadFragments in merge order
+func LoadedFiles() []string {
+	files := make([]string, len(loadedFragments))
+	for i, f := range loadedFragments {
+		files[i] = f.Source
+	}
+	return files
+}
+
+// ApplyOverlay applies configuration fragments on top of the loaded configuration, including presets.
+// Settings given via flags or environment variables keep precedence, `images` and `presets` are ignored.
+// The returned function restores the previous configuration.
+func ApplyOverlay(fragments []Fragment) func() {
+	type previousValue struct {
+		value interface{}
+		set   bool
+	}
+	previous := map[string]previousValue{}
+
+	restore := func() {
+		for key, p := range previous {
+			if p.set {
+				viper.Set(key, p.value)
+			} else {
+				// a nil override is ignored by viper, thus unsets the value
+				viper.Set(key, nil)
+			}
+		}
+	}
+
+	for _, f := range fragments {
+		for key, value := range f.Settings {
+			if key == "images" || key == "presets" || setByFlagOrEnv(key) {
+				continue
+			}
+			if _, ok := previous[key]; !ok {
+				previous[key] = previousValue{value: viper.Get(key), set: viper.IsSet(key)}
+			}
+			viper.Set(key, mergeValue(viper.Get(key), value))
+		}
+	}
+
+	return restore
+}
+
+// mergeValue merges nested settings key by key and replaces all other values
+func mergeValue(base interface{}, value interface{}) interface{} {
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	merged := map[string]interface{}{}
+	for k, v := range baseMap {
+		merged[k] = v
+	}
+	for k, v := range valueMap {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+func TestDiscoverFiles(t *testing.T) {
+	root := withFiles(t, map[string]string{
+		"home/.draide.yaml":            "push: false\n",
+		"repo/.draide.yaml":            "registry: r\n",
+		"repo/svc/a/.draide.json":      "{}\n",
+		"repo/svc/a/src/main.go":       "package main\n",
+		"repo/svc/b/.draide.yaml":      "namespace: b\n",
+		"outside/.draide.yaml":         "push: true\n",
+		"outside/nested/.draide.yaml":  "push: true\n",
+		"outside/nested/deeper/README": "\n",
+	})
+	if _, err := git.PlainInit(filepath.Join(root, "repo"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		home string
+		want []string
+	}{
+		{dir: "repo", want: []string{"repo/.draide.yaml"}},
+		{dir: "repo/svc/a", want: []string{"repo/.draide.yaml", "repo/svc/a/.draide.json"}},
+		{dir: "repo/svc/a/src", want: []string{"repo/.draide.yaml", "repo/svc/a/.draide.json"}},
+		{dir: "repo/svc/a", home: "home", want: []string{"home/.draide.yaml", "repo/.draide.yaml", "repo/svc/a/.draide.json"}},
+		{dir: "repo", home: "repo", want: []string{"repo/.draide.yaml"}},
+		// outside of a git repository, parent directories are not searched
+		{dir: "outside/nested/deeper", want: []string{}},
+		{dir: "outside/nested", want: []string{"outside/nested/.draide.yaml"}},
+	}
+	for _, tt := range tests {
+		home := ""
+		if tt.home != "" {
+			home = filepath.Join(root, tt.home)
+		}
+		files, err := DiscoverFiles(filepath.Join(root, filepath.FromSlash(tt.dir)), home)
+		if err != nil {
+			t.Errorf("DiscoverFiles(%s) error = %v", tt.dir, err)
+			continue
+		}
+
+		got := []string{}
+		for _, f := range files {
+			rel, _ := filepath.Rel(root, f)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DiscoverFiles(%s, %q) = %v, want %v", tt.dir, tt.home, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFragments(t *testing.T) {
+	withConfig(t, "")
+	fragments := []Fragment{
+		{Source: "/repo/.draide.yaml", Dir: "/repo", Settings: map[string]interface{}{
+			"registry":   "r",
+			"watchpaths": []interface{}{"shared"},
+			"labels":     map[string]interface{}{"team": "core", "tier": "base"},
+		}},
+		{Source: "/repo/svc/.draide.yaml", Dir: "/repo/svc", Settings: map[string]interface{}{
+			"registry": "s",
+			"labels":   map[string]interface{}{"tier": "svc"},
+		}},
+	}
+
+	if err := LoadFragments(fragments); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := viper.GetString("registry"); got != "s" {
+		t.Errorf("registry = %q", got)
+	}
+	if got := viper.GetStringMapString("labels"); !reflect.DeepEqual(got, map[string]string{"team": "core", "tier": "svc"}) {
+		t.Errorf("labels = %v", got)
+	}
+	if got := LoadedFiles(); strings.Join(got, ",") != "/repo/.draide.yaml,/repo/svc/.draide.yaml" {
+		t.Errorf("LoadedFiles() = %v", got)
+	}
+
+	tests := map[string]string{
+		"registry":   "/repo/svc",
+		"watchPaths": "/repo",
+		"labels":     "/repo/svc",
+		"images":     "",
+	}
+	for key, want := range tests {
+		if got := SettingDir(key); got != want {
+			t.Errorf("SettingDir(%s) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/gittools"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// Fragment is a configuration file or a file included by one via the `include` directive
+type Fragment struct {
+	// Source names the fragment: a file path or <repo>@<revision>:<path> for files read from a git revision
+	Source string
+	// IncludedBy is the source of the fragment including this one, empty for configuration files not included
+	IncludedBy string
+	// Dir is the directory relative paths in the settings are resolved against: the directory of the file, or for
+	// fragments read from git, the directory of the file including them
+	Dir     string
+	Content []byte
+	// Settings contains the settings of the fragment without the `include` directive
+	Settings map[string]interface{}
+}
+
+// ParseError reports a configuration fragment which cannot be parsed
+type ParseError struct {
+	Source  string
+	Content []byte
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed parsing %s: %v", e.Source, e.Err)
+}
+
+// includeSpec is an entry of the `include` directive, either a path or a file pinned to a revision of a local git clone
+type includeSpec struct {
+	Path     string
+	Repo     string
+	Revision string
+}
+
+// location identifies where a fragment is read from
+type location struct {
+	// path is an absolute file path, or a path relative to the repository root for fragments read from git
+	path     string
+	repo     string
+	revision string
+}
+
+func (l location) source() string {
+	if l.repo == "" {
+		return l.path
+	}
+	return fmt.Sprintf("%s@%s:%s", l.repo, l.revision, l.path)
+}
+
+func (l location) read() ([]byte, error) {
+	if l.repo == "" {
+		return ioutil.ReadFile(l.path)
+	}
+	return gittools.ReadFileAtRevision(l.repo, l.revision, l.path)
+}
+
+// resolve returns the location of a file included by the fragment at l.
+// Relative paths are relative to the including file, relative includes of a fragment read from git are read from the same revision.
+func (l location) resolve(spec includeSpec) (location, error) {
+	if spec.Path == "" {
+		return location{}, fmt.Errorf("include without path")
+	}
+
+	if spec.Repo == "" {
+		if l.repo != "" {
+			return location{path: path.Join(path.Dir(l.path), filepath.ToSlash(spec.Path)), repo: l.repo, revision: l.revision}, nil
+		}
+		p, err := homedir.Expand(spec.Path)
+		if err != nil {
+			return location{}, err
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(l.path), p)
+		}
+		return location{path: p}, nil
+	}
+
+	if spec.Revision == "" {
+		return location{}, fmt.Errorf("include of %s from %s requires a revision", spec.Path, spec.Repo)
+	}
+	repo, err := homedir.Expand(spec.Repo)
+	if err != nil {
+		return location{}, err
+	}
+	if !filepath.IsAbs(repo) {
+		base := filepath.Dir(l.path)
+		if l.repo != "" {
+			base = l.repo
+		}
+		repo = filepath.Join(base, repo)
+	}
+	return location{path: strings.TrimPrefix(path.Clean(filepath.ToSlash(spec.Path)), "/"), repo: repo, revision: spec.Revision}, nil
+}
+
+// ResolveFiles reads the given configuration files and the files they include.
+// The result lists every fragment once in merge order: included files come before the file including them.
+func ResolveFiles(paths []string) ([]Fragment, error) {
+	r := resolver{visiting: map[string]bool{}, done: map[string]bool{}, dirs: map[string]string{}, fragments: []Fragment{}}
+
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.visit(location{path: p}, []string{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.fragments, nil
+}
+
+type resolver struct {
+	visiting  map[string]bool
+	done      map[string]bool
+	dirs      map[string]string
+	fragments []Fragment
+}
+
+func (r *resolver) visit(loc location, chain []string) error {
+	source := loc.source()
+	if r.visiting[source] {
+		return fmt.Errorf("cyclic include: %s", strings.Join(append(chain, source), " -> "))
+	}
+	if r.done[source] {
+		return nil
+	}
+
+	includedBy := ""
+	if len(chain) > 0 {
+		includedBy = chain[len(chain)-1]
+	}
+	dir := filepath.Dir(loc.path)
+	if loc.repo != "" {
+		dir = r.dirs[includedBy]
+	}
+	r.dirs[source] = dir
+
+	content, err := loc.read()
+	if err != nil {
+		if includedBy != "" {
+			return fmt.Errorf("failed including %s from %s: %v", source, includedBy, err)
+		}
+		return err
+	}
+
+	v := viper.New()
+	v.SetConfigType(strings.TrimPrefix(filepath.Ext(loc.path), "."))
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return &ParseError{Source: source, Content: content, Err: err}
+	}
+	settings := v.AllSettings()
+	specs, err := includeSpecs(settings["include"])
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	delete(settings, "include")
+
+	r.visiting[source] = true
+	for _, spec := range specs {
+		included, err := loc.resolve(spec)
+		if err != nil {
+			return fmt.Errorf("%s: %v", source, err)
+		}
+		if err := r.visit(included, append(chain, source)); err != nil {
+			return err
+		}
+	}
+	r.visiting[source] = false
+	r.done[source] = true
+
+	r.fragments = append(r.fragments, Fragment{Source: source, IncludedBy: includedBy, Dir: dir, Content: content, Settings: settings})
+	return nil
+}
+
+// includeSpecs parses the `include` directive, a path or a list of paths and {path, repo, revision} maps
+func includeSpecs(value interface{}) ([]includeSpec, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case nil:
+		return []includeSpec{}, nil
+	case string:
+		items = []interface{}{v}
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("include must be a path or a list")
+	}
+
+	specs := []includeSpec{}
+	for _, item := range items {
+		switch i := item.(type) {
+		case string:
+			specs = append(specs, includeSpec{Path: i})
+		case map[interface{}]interface{}, map[string]interface{}:
+			spec := includeSpec{}
+			for k, v := range flatten("", map[string]interface{}{"include": i}) {
+				switch strings.TrimPrefix(k, "include.") {
+				case "path":
+					spec.Path = fmt.Sprint(v)
+				case "repo":
+					spec.Repo = fmt.Sprint(v)
+				case "revision":
+					spec.Revision = fmt.Sprint(v)
+				default:
+					return nil, fmt.Errorf("unknown include option %s", strings.TrimPrefix(k, "include."))
+				}
+			}
+			specs = append(specs, spec)
+		default:
+			return nil, fmt.Errorf("include entries must be paths or maps")
+		}
+	}
+
+	return specs, nil
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// withFiles creates a directory tree of files with the given content and returns its root
+func withFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "draide-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ = filepath.EvalSymlinks(root)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestResolveFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want lists the fragment sources relative to the root, in merge order
+		want []string
+		err  string
+	}{
+		{
+			name:  "no include",
+			files: map[string]string{".draide.yaml": "push: true\n"},
+			want:  []string{".draide.yaml"},
+		},
+		{
+			name: "nested includes relative to the including file",
+			files: map[string]string{
+				".draide.yaml":       "include: config/base.yaml\n",
+				"config/base.yaml":   "include: [common.yaml]\nregistry: r\n",
+				"config/common.yaml": "namespace: n\n",
+				// a file next to the configuration file, which must not be picked for the include of config/base.yaml
+				"common.yaml": "push: true\n",
+			},
+			want: []string{"config/common.yaml", "config/base.yaml", ".draide.yaml"},
+		},
+		{
+			name: "files included twice are loaded once",
+			files: map[string]string{
+				".draide.yaml": "include: [a.yaml, b.yaml]\n",
+				"a.yaml":       "include: common.yaml\n",
+				"b.yaml":       "include: common.yaml\n",
+				"common.yaml":  "push: true\n",
+			},
+			want: []string{"common.yaml", "a.yaml", "b.yaml", ".draide.yaml"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				".draide.yaml": "include: a.yaml\n",
+				"a.yaml":       "include: b.yaml\n",
+				"b.yaml":       "include: a.yaml\n",
+			},
+			err: "cyclic include: ROOT/.draide.yaml -> ROOT/a.yaml -> ROOT/b.yaml -> ROOT/a.yaml",
+		},
+		{
+			name:  "self include",
+			files: map[string]string{".draide.yaml": "include: .draide.yaml\n"},
+			err:   "cyclic include: ROOT/.draide.yaml -> ROOT/.draide.yaml",
+		},
+		{
+			name:  "missing file",
+			files: map[string]string{".draide.yaml": "include: missing.yaml\n"},
+			err:   "failed including ROOT/missing.yaml from ROOT/.draide.yaml",
+		},
+		{
+			name:  "revision without repo",
+			files: map[string]string{".draide.yaml": "include:\n  - path: a.yaml\n    repo: ../other\n"},
+			err:   "ROOT/.draide.yaml: include of a.yaml from ../other requires a revision",
+		},
+		{
+			name:  "unknown option",
+			files: map[string]string{".draide.yaml": "include:\n  - path: a.yaml\n    branch: main\n"},
+			err:   "ROOT/.draide.yaml: unknown include option branch",
+		},
+		{
+			name:  "invalid include",
+			files: map[string]string{".draide.yaml": "include: true\n"},
+			err:   "ROOT/.draide.yaml: include must be a path or a list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := withFiles(t, tt.files)
+			fragments, err := ResolveFiles([]string{filepath.Join(root, ".draide.yaml")})
+			if tt.err != "" {
+				want := strings.Replace(tt.err, "ROOT", root, -1)
+				if err == nil || !strings.HasPrefix(err.Error(), want) {
+					t.Errorf("ResolveFiles() error = %v, want %q", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, f := range fragments {
+				rel, _ := filepath.Rel(root, f.Source)
+				got = append(got, filepath.ToSlash(rel))
+				if f.Dir != filepath.Dir(f.Source) {
+					t.Errorf("Dir of %s = %s", f.Source, f.Dir)
+				}
+				if _, ok := f.Settings["include"]; ok {
+					t.Errorf("settings of %s contain the include directive", f.Source)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFilesFromRevision(t *testing.T) {
+	root := withFiles(t, map[string]string{
+		"shared/ci/draide.yaml": "include: common.yaml\nregistry: r\n",
+		"shared/ci/common.yaml": "namespace: committed\n",
+	})
+	repo, err := git.PlainInit(filepath.Join(root, "shared"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ci/draide.yaml", "ci/common.yaml"} {
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "draide", Email: "draide@example.com", When: time.Now()}
+	if _, err := worktree.Commit("init", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+	// the included file is read from the revision, not from the worktree
+	if err := ioutil.WriteFile(filepath.Join(root, "shared/ci/common.yaml"), []byte("namespace: changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revision := head.Name().Short()
+	content := "include:\n  - path: ci/draide.yaml\n    repo: shared\n    revision: " + revision + "\n"
+	if err := ioutil.WriteFile(filepath.Join(root, ".draide.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fragments, err := ResolveFiles([]string{filepath.Join(root, ".draide.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := filepath.Join(root, "shared")
+	want := []string{
+		shared + "@" + revision + ":ci/common.yaml",
+		shared + "@" + revision + ":ci/draide.yaml",
+		filepath.Join(root, ".draide.yaml"),
+	}
+	got := []string{}
+	for _, f := range fragments {
+		got = append(got, f.Source)
+		// fragments read from git resolve paths against the directory of the file including them
+		if f.Dir != root {
+			t.Errorf("Dir of %s = %s, want %s", f.Source, f.Dir, root)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveFiles() = %v, want %v", got, want)
+	}
+	if ns := fragments[0].Settings["namespace"]; ns != "committed" {
+		t.Errorf("namespace = %v, want the committed value", ns)
+	}
+}
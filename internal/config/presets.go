@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/types"
+
+	"github.com/spf13/viper"
+)
+
+// presetDirectives are preset keys with merge semantics of their own, which are not merged into the configuration as is
+var presetDirectives = map[string]bool{
+	"extends":        true,
+	"extratags":      true,
+	"extrabuildargs": true,
+	"extralabels":    true,
+}
+
+var appliedPresets = []string{}
+
+// overriddenKeys tracks settings which were extended by a preset directive and thus live in viper's override layer
+var overriddenKeys = map[string]bool{}
+
+// ParsePresetNames splits a comma separated list of preset names
+func ParsePresetNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ResolvePresets expands the `extends` declarations of the given presets.
+// The result lists every preset once in application order: presets a preset extends come before the preset itself.
+func ResolvePresets(names []string) ([]string, error) {
+	resolved := []string{}
+	state := map[string]int{} // 1: visiting, 2: done
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("cyclic preset inheritance: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+
+		key := "presets." + name
+		if !viper.IsSet(key) {
+			return fmt.Errorf("unable to find preset configuration for: %s", name)
+		}
+
+		state[name] = 1
+		for _, parent := range viper.GetStringSlice(key + ".extends") {
+			if err := visit(parent, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		resolved = append(resolved, name)
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, []string{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return resolved, nil
+}
+
+// ApplyPresets merges the given presets, including the presets they extend, into the configuration.
+//
+// Presets are applied in order and settings of a later preset override earlier values.
+// `extraTags` are appended to the tags, `extraBuildArgs` are appended to the build arguments (replacing an existing
+// build argument with the same key) and `extraLabels` are merged into the labels.
+func ApplyPresets(names []string) error {
+	resolved, err := ResolvePresets(names)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range resolved {
+		if err := applyPreset(name); err != nil {
+			return fmt.Errorf("preset %s: %v", name, err)
+		}
+		appliedPresets = append(appliedPresets, name)
+	}
+
+	return nil
+}
+
+// AppliedPresets returns the presets applied to the configuration in application order
+func AppliedPresets() []string {
+	return appliedPresets
+}
+
+func applyPreset(name string) error {
+	presetSettings := viper.Sub("presets." + name)
+	if presetSettings == nil {
+		return fmt.Errorf("preset is not a map")
+	}
+
+	settings := map[string]interface{}{}
+	for k, v := range presetSettings.AllSettings() {
+		if !presetDirectives[k] {
+			settings[k] = v
+		}
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	RecordLayer("preset "+name, "set", settings)
+	for k, v := range settings {
+		// a value extended by an earlier preset would otherwise shadow the merged one
+		if overriddenKeys[k] {
+			viper.Set(k, v)
+		}
+	}
+
+	if presetSettings.IsSet("extraBuildArgs") {
+		var buildArgs, buildArgsOfPreset []types.KeyValueConfig
+
+		// unmarshal values into an interface as a workaround to enable case-sensitive data loading from config file
+		// ref: https://github.com/spf13/viper/issues/373
+		if err := viper.UnmarshalKey("buildArgs", &buildArgs); err != nil {
+			return fmt.Errorf("failed parsing build arguments: %v", err)
+		}
+		if err := presetSettings.UnmarshalKey("extraBuildArgs", &buildArgsOfPreset); err != nil {
+			return fmt.Errorf("failed parsing extra build arguments: %v", err)
+		}
+		viper.Set("buildArgs", mergeKeyValues(buildArgs, buildArgsOfPreset))
+		contribute("buildargs", Contribution{Source: "preset " + name, Mode: "append", Value: buildArgsOfPreset})
+		overriddenKeys["buildargs"] = true
+	}
+
+	if presetSettings.IsSet("extraTags") {
+		tags := viper.GetStringSlice("tags")
+		extraTags := presetSettings.GetStringSlice("extraTags")
+		contribute("tags", Contribution{Source: "preset " + name, Mode: "append", Value: extraTags})
+		for _, tag := range extraTags {
+			if !contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		viper.Set("tags", tags)
+		overriddenKeys["tags"] = true
+	}
+
+	if presetSettings.IsSet("extraLabels") {
+		labels := map[string]string{}
+		for k, v := range viper.GetStringMapString("labels") {
+			labels[k] = v
+		}
+		for k, v := range presetSettings.GetStringMapString("extraLabels") {
+			labels[k] = v
+			contribute("labels."+k, Contribution{Source: "preset " + name, Mode: "merge", Value: v})
+		}
+		viper.Set("labels", labels)
+		overriddenKeys["labels"] = true
+	}
+
+	return nil
+}
+
+// mergeKeyValues appends key value pairs, replacing existing entries with the same key
+func mergeKeyValues(base []types.KeyValueConfig, extra []types.KeyValueConfig) []types.KeyValueConfig {
+	merged := append([]types.KeyValueConfig{}, base...)
+
+	for _, e := range extra {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == e.Key {
+				merged[i].Value = e.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, e)
+		}
+	}
+
+	return merged
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/types"
+
+	"github.com/spf13/viper"
+)
+
+// withConfig loads a YAML configuration into viper and resets viper and the package state once the test ends
+func withConfig(t *testing.T, content string) {
+	t.Helper()
+	reset := func() {
+		viper.Reset()
+		appliedPresets = []string{}
+		overriddenKeys = map[string]bool{}
+		matchedRules = []string{}
+		pushRule = ""
+		loadedFragments = []Fragment{}
+		contributions = map[string][]Contribution{}
+	}
+	reset()
+	t.Cleanup(reset)
+
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(bytes.NewBufferString(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvePresets(t *testing.T) {
+	withConfig(t, `
+presets:
+  base: {}
+  ci:
+    extends: [base]
+  release:
+    extends: [ci, base]
+  loop-a:
+    extends: [loop-b]
+  loop-b:
+    extends: [loop-a]
+  broken:
+    extends: [missing]
+`)
+
+	tests := []struct {
+		names []string
+		want  []string
+		err   string
+	}{
+		{names: []string{"base"}, want: []string{"base"}},
+		{names: []string{"ci"}, want: []string{"base", "ci"}},
+		{names: []string{"release"}, want: []string{"base", "ci", "release"}},
+		{names: []string{"ci", "release"}, want: []string{"base", "ci", "release"}},
+		{names: []string{"loop-a"}, err: "cyclic preset inheritance: loop-a -> loop-b -> loop-a"},
+		{names: []string{"broken"}, err: "unable to find preset configuration for: missing"},
+		{names: []string{"unknown"}, err: "unable to find preset configuration for: unknown"},
+	}
+	for _, tt := range tests {
+		got, err := ResolvePresets(tt.names)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ResolvePresets(%v) error = %v, want %q", tt.names, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolvePresets(%v) error = %v", tt.names, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolvePresets(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestApplyPresets(t *testing.T) {
+	withConfig(t, `
+tags: [latest]
+buildArgs:
+  - key: VERSION
+    value: "1"
+labels:
+  team: core
+presets:
+  base:
+    push: true
+    extraTags: [stable]
+    extraLabels:
+      tier: base
+  ci:
+    extends: [base]
+    registry: registry.example.com
+    extraTags: [ci, stable]
+    extraBuildArgs:
+      - key: VERSION
+        value: "2"
+      - key: CI
+        value: "true"
+`)
+
+	if err := ApplyPresets([]string{"ci"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := AppliedPresets(); !reflect.DeepEqual(got, []string{"base", "ci"}) {
+		t.Errorf("AppliedPresets() = %v", got)
+	}
+	if !viper.GetBool("push") || viper.GetString("registry") != "registry.example.com" {
+		t.Errorf("push = %v, registry = %q", viper.GetBool("push"), viper.GetString("registry"))
+	}
+	if got := viper.GetStringSlice("tags"); !reflect.DeepEqual(got, []string{"latest", "stable", "ci"}) {
+		t.Errorf("tags = %v", got)
+	}
+	if got := viper.GetStringMapString("labels"); !reflect.DeepEqual(got, map[string]string{"team": "core", "tier": "base"}) {
+		t.Errorf("labels = %v", got)
+	}
+	wantBuildArgs := []types.KeyValueConfig{{Key: "VERSION", Value: "2"}, {Key: "CI", Value: "true"}}
+	if got := viper.Get("buildArgs"); !reflect.DeepEqual(got, wantBuildArgs) {
+		t.Errorf("buildArgs = %v, want %v", got, wantBuildArgs)
+	}
+}
+
+func TestApplyPresetsNotAMap(t *testing.T) {
+	withConfig(t, `
+presets:
+  flat: true
+`)
+
+	err := ApplyPresets([]string{"flat"})
+	if err == nil || err.Error() != "preset flat: preset is not a map" {
+		t.Errorf("ApplyPresets() error = %v", err)
+	}
+}
+
+func TestParsePresetNames(t *testing.T) {
+	tests := map[string][]string{
+		"":              {},
+		"ci":            {"ci"},
+		" ci , release": {"ci", "release"},
+		"ci,,release,":  {"ci", "release"},
+	}
+	for value, want := range tests {
+		if got := ParsePresetNames(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePresetNames(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
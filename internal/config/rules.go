@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Rule changes tags, presets or the push policy if its conditions match the current build
+type Rule struct {
+	Name       string
+	When       RuleCondition
+	AddTags    []string `mapstructure:"addTags"`
+	RemoveTags []string `mapstructure:"removeTags"`
+	Push       *bool
+	Presets    []string
+}
+
+// RuleCondition lists the conditions of a rule, all given conditions must match.
+// Patterns are globs, such as release/*, or regular expressions enclosed in slashes, such as /^v\d+$/.
+type RuleCondition struct {
+	// Branch is a pattern or a list of patterns, one of which must match the branch name
+	Branch interface{}
+	// Tag is either a boolean requiring a git tag to be present or absent, or a pattern matching the tag
+	Tag   interface{}
+	Dirty *bool
+	// Env maps environment variable names to patterns matching their values
+	Env map[string]string
+}
+
+// RuleContext describes the build rules are evaluated against
+type RuleContext struct {
+	Branch    string
+	Tag       string
+	Dirty     func() bool
+	LookupEnv func(name string) (string, bool)
+}
+
+var matchedRules = []string{}
+
+var pushRule = ""
+
+// ApplyRules evaluates the rules of the configuration in order and applies the matching ones.
+// Rules take precedence over all other configuration layers including flags.
+func ApplyRules(ctx RuleContext) error {
+	var rules []Rule
+	if err := viper.UnmarshalKey("rules", &rules); err != nil {
+		return fmt.Errorf("failed parsing rules: %v", err)
+	}
+
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		matches, err := rule.When.matches(ctx)
+		if err != nil {
+			return fmt.Errorf("rule %s: %v", name, err)
+		}
+		if !matches {
+			continue
+		}
+		matchedRules = append(matchedRules, name)
+		source := "rule " + name
+
+		if len(rule.Presets) > 0 {
+			if err := ApplyPresets(rule.Presets); err != nil {
+				return fmt.Errorf("rule %s: %v", name, err)
+			}
+		}
+
+		if len(rule.AddTags) > 0 || len(rule.RemoveTags) > 0 {
+			tags := []string{}
+			for _, tag := range viper.GetStringSlice("tags") {
+				if !contains(rule.RemoveTags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			for _, tag := range rule.AddTags {
+				if !contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			if len(rule.RemoveTags) > 0 {
+				contribute("tags", Contribution{Source: source, Mode: "remove", Value: rule.RemoveTags})
+			}
+			if len(rule.AddTags) > 0 {
+				contribute("tags", Contribution{Source: source, Mode: "append", Value: rule.AddTags})
+			}
+			viper.Set("tags", tags)
+			overriddenKeys["tags"] = true
+		}
+
+		if rule.Push != nil {
+			viper.Set("push", *rule.Push)
+			contribute("push", Contribution{Source: source, Mode: "set", Value: *rule.Push})
+			pushRule = name
+		}
+	}
+
+	return nil
+}
+
+// MatchedRules returns the names of the rules which matched, unnamed rules are named by their position
+func MatchedRules() []string {
+	return matchedRules
+}
+
+// PushRule returns the name of the last matching rule setting the push policy, empty if no rule did
+func PushRule() string {
+	return pushRule
+}
+
+func (c RuleCondition) matches(ctx RuleContext) (bool, error) {
+	if c.Branch != nil {
+		patterns, err := stringOrList(c.Branch)
+		if err != nil {
+			return false, fmt.Errorf("branch: %v", err)
+		}
+		matched := false
+		for _, pattern := range patterns {
+			ok, err := matchPattern(pattern, ctx.Branch)
+			if err != nil {
+				return false, err
+			}
+			matched = matched || ok
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	switch tag := c.Tag.(type) {
+	case nil:
+	case bool:
+		if tag != (ctx.Tag != "") {
+			return false, nil
+		}
+	case string:
+		ok, err := matchPattern(tag, ctx.Tag)
+		if err != nil || !ok || ctx.Tag == "" {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("tag must be a boolean or a pattern")
+	}
+
+	if c.Dirty != nil && *c.Dirty != ctx.Dirty() {
+		return false, nil
+	}
+
+	for name, pattern := range c.Env {
+		value, _ := ctx.LookupEnv(name)
+		ok, err := matchPattern(pattern, value)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchPattern matches a value against a glob or a regular expression enclosed in slashes
+func matchPattern(pattern string, value string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %s: %v", pattern, err)
+		}
+		return re.MatchString(value), nil
+	}
+
+	matched, err := path.Match(pattern, value)
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+	}
+	return matched, nil
+}
+
+func stringOrList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			list[i] = fmt.Sprint(item)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected a pattern or a list of patterns")
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func ruleContext(branch string, tag string, dirty bool, env map[string]string) RuleContext {
+	return RuleContext{
+		Branch: branch,
+		Tag:    tag,
+		Dirty:  func() bool { return dirty },
+		LookupEnv: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+	}
+}
+
+func TestRuleConditionMatches(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name string
+		when RuleCondition
+		ctx  RuleContext
+		want bool
+		err  string
+	}{
+		{name: "no condition", when: RuleCondition{}, ctx: ruleContext("main", "", false, nil), want: true},
+		{name: "branch glob", when: RuleCondition{Branch: "release/*"}, ctx: ruleContext("release/1.0", "", false, nil), want: true},
+		{name: "branch glob mismatch", when: RuleCondition{Branch: "release/*"}, ctx: ruleContext("main", "", false, nil), want: false},
+		{name: "branch list", when: RuleCondition{Branch: []interface{}{"main", "master"}}, ctx: ruleContext("master", "", false, nil), want: true},
+		{name: "branch regexp", when: RuleCondition{Branch: `/^feature-\d+$/`}, ctx: ruleContext("feature-12", "", false, nil), want: true},
+		{name: "invalid branch regexp", when: RuleCondition{Branch: "/(/"}, ctx: ruleContext("main", "", false, nil), err: "invalid regular expression /(/: error parsing regexp: missing closing ): `(`"},
+		{name: "invalid branch glob", when: RuleCondition{Branch: "[main"}, ctx: ruleContext("main", "", false, nil), err: "invalid pattern [main: syntax error in pattern"},
+		{name: "invalid branch", when: RuleCondition{Branch: 1}, ctx: ruleContext("main", "", false, nil), err: "branch: expected a pattern or a list of patterns"},
+		{name: "tag required", when: RuleCondition{Tag: true}, ctx: ruleContext("main", "v1.0.0", false, nil), want: true},
+		{name: "tag required but absent", when: RuleCondition{Tag: true}, ctx: ruleContext("main", "", false, nil), want: false},
+		{name: "tag absent", when: RuleCondition{Tag: false}, ctx: ruleContext("main", "", false, nil), want: true},
+		{name: "tag pattern", when: RuleCondition{Tag: `/^v\d+\.\d+\.\d+$/`}, ctx: ruleContext("main", "v1.0.0", false, nil), want: true},
+		{name: "tag pattern without tag", when: RuleCondition{Tag: "*"}, ctx: ruleContext("main", "", false, nil), want: false},
+		{name: "invalid tag", when: RuleCondition{Tag: 1}, ctx: ruleContext("main", "", false, nil), err: "tag must be a boolean or a pattern"},
+		{name: "dirty", when: RuleCondition{Dirty: &yes}, ctx: ruleContext("main", "", true, nil), want: true},
+		{name: "clean", when: RuleCondition{Dirty: &no}, ctx: ruleContext("main", "", true, nil), want: false},
+		{name: "env", when: RuleCondition{Env: map[string]string{"CI": "true"}}, ctx: ruleContext("main", "", false, map[string]string{"CI": "true"}), want: true},
+		{name: "env unset", when: RuleCondition{Env: map[string]string{"CI": "?*"}}, ctx: ruleContext("main", "", false, nil), want: false},
+		{name: "all conditions", when: RuleCondition{Branch: "main", Dirty: &no, Env: map[string]string{"CI": "true"}}, ctx: ruleContext("main", "", true, map[string]string{"CI": "true"}), want: false},
+	}
+	for _, tt := range tests {
+		got, err := tt.when.matches(tt.ctx)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: matches() error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: matches() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	withConfig(t, `
+tags: [latest, edge]
+presets:
+  release:
+    registry: registry.example.com
+rules:
+  - name: main
+    when:
+      branch: main
+    addTags: [main]
+    removeTags: [edge]
+  - when:
+      tag: true
+    presets: [release]
+    push: true
+  - name: never
+    when:
+      branch: other
+    push: false
+  - name: dirty
+    when:
+      dirty: true
+    push: false
+`)
+
+	if err := ApplyRules(ruleContext("main", "v1.0.0", false, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := MatchedRules(); !reflect.DeepEqual(got, []string{"main", "#2"}) {
+		t.Errorf("MatchedRules() = %v", got)
+	}
+	if got := viper.GetStringSlice("tags"); !reflect.DeepEqual(got, []string{"latest", "main"}) {
+		t.Errorf("tags = %v", got)
+	}
+	if !viper.GetBool("push") || PushRule() != "#2" {
+		t.Errorf("push = %v, PushRule() = %q", viper.GetBool("push"), PushRule())
+	}
+	if got := viper.GetString("registry"); got != "registry.example.com" {
+		t.Errorf("registry = %q", got)
+	}
+	if got := AppliedPresets(); !reflect.DeepEqual(got, []string{"release"}) {
+		t.Errorf("AppliedPresets() = %v", got)
+	}
+}
+
+func TestApplyRulesInvalidPattern(t *testing.T) {
+	withConfig(t, `
+rules:
+  - name: broken
+    when:
+      branch: /(/
+`)
+
+	err := ApplyRules(ruleContext("main", "", false, nil))
+	if err == nil || err.Error() != "rule broken: invalid regular expression /(/: error parsing regexp: missing closing ): `(`" {
+		t.Errorf("ApplyRules() error = %v", err)
+	}
+}
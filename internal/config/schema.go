@@ -0,0 +1,266 @@
+package config
+
+import (
+	"sort"
+)
+
+// Schema describes the structure of a configuration value
+type Schema struct {
+	// Types lists the allowed types: object, array, string, boolean or integer. Multiple types are allowed for values like `extends`.
+	Types       []string
+	Description string
+	// Properties lists the known keys of an object
+	Properties map[string]*Schema
+	// AdditionalProperties describes the values of an object with arbitrary keys, like labels
+	AdditionalProperties *Schema
+	// Items describes the elements of an array
+	Items *Schema
+	// Required lists keys which must be present in an object
+	Required []string
+	// Enum restricts a string to a set of values
+	Enum []string
+}
+
+func stringSchema(description string) *Schema {
+	return &Schema{Types: []string{"string"}, Description: description}
+}
+
+func booleanSchema(description string) *Schema {
+	return &Schema{Types: []string{"boolean"}, Description: description}
+}
+
+func integerSchema(description string) *Schema {
+	return &Schema{Types: []string{"integer"}, Description: description}
+}
+
+func stringListSchema(description string) *Schema {
+	return &Schema{Types: []string{"array"}, Description: description, Items: &Schema{Types: []string{"string"}}}
+}
+
+func stringMapSchema(description string) *Schema {
+	return &Schema{Types: []string{"object"}, Description: description, AdditionalProperties: &Schema{Types: []string{"string"}}}
+}
+
+func objectSchema(description string, properties map[string]*Schema) *Schema {
+	return &Schema{Types: []string{"object"}, Description: description, Properties: properties}
+}
+
+func keyValueListSchema(description string) *Schema {
+	return &Schema{
+		Types:       []string{"array"},
+		Description: description,
+		Items: &Schema{
+			Types:    []string{"object"},
+			Required: []string{"key"},
+			Properties: map[string]*Schema{
+				"key":   stringSchema("Name"),
+				"value": stringSchema("Value. May contain template variables."),
+			},
+		},
+	}
+}
+
+// settingsProperties returns the settings which may appear both at the root of the configuration and inside presets
+func settingsProperties() map[string]*Schema {
+	return map[string]*Schema{
+		"verbose": booleanSchema("Logging verbosity"),
+		"logFormat": {
+			Types:       []string{"string"},
+			Description: "Log format. JSON emits one event per line to stderr and the final tags or digests to stdout.",
+			Enum:        []string{"text", "json"},
+		},
+		"quiet": booleanSchema("Only print errors and the final tags or digests"),
+		"color": {
+			Types:       []string{"string"},
+			Description: "Colour diagnostics. auto colours if stderr is a terminal and NO_COLOR is not set.",
+			Enum:        []string{"auto", "always", "never"},
+		},
+		"ci": {
+			Types:       []string{"string"},
+			Description: "CI provider output is adapted to. auto detects GitHub Actions and GitLab CI.",
+			Enum:        []string{"auto", "none", "github", "gitlab"},
+		},
+		"ciOutputFile":      stringSchema("File image IDs, digests and tags are appended to. Defaults to $GITHUB_OUTPUT on GitHub Actions and to $CI_PROJECT_DIR/.draide/outputs.env on GitLab CI, which is overwritten per run and meant to be declared as artifacts:reports:dotenv."),
+		"imageName":         stringSchema("Image name. Defaults to the name of the current directory."),
+		"registry":          stringSchema("Container registry, such as: k8s.gcr.io"),
+		"namespace":         stringSchema("Repository namespace"),
+		"repository-format": stringSchema("Format to construct repository name. May contain template variables."),
+		"tags":              stringListSchema("Image tags. May contain template variables."),
+		"username":          stringSchema("Username for pushing image into registry"),
+		"password":          stringSchema("Password for pushing image into registry"),
+		"reportFile":        stringSchema("Write a JSON report of the run to the given file"),
+		"metricsFile":       stringSchema("Write build and push timings to the given file in OpenMetrics text format"),
+		"junitFile":         stringSchema("Write the results of the checks of the test section to the given file in JUnit XML format"),
+		"run": objectSchema("Options of the container started by the run command", map[string]*Schema{
+			"ports":   stringListSchema("Published ports in docker run syntax, such as 8080:80"),
+			"env":     keyValueListSchema("Environment variables. Values may contain template variables."),
+			"volumes": stringListSchema("Bind mounts or named volumes in docker run syntax, such as ./data:/data"),
+			"network": stringSchema("Network to connect the container to"),
+		}),
+		"dockerfile":         stringSchema("Path to Dockerfile relative to the context directory. May contain template variables."),
+		"nocache":            booleanSchema("Build without cache"),
+		"push":               booleanSchema("Push images after building"),
+		"pushRetries":        integerSchema("Number of retries if pushing an image fails"),
+		"detailedExitCode":   booleanSchema("Exit with code 7 instead of 0 if there is nothing to build or push"),
+		"labels":             stringMapSchema("Image labels. Values may contain template variables."),
+		"buildArgs":          keyValueListSchema("Build arguments"),
+		"skipExisting":       booleanSchema("Skip building and testing if an image with the identity tag already exists in the registry. When pushing, the remaining tags are added to the existing image."),
+		"identityTag":        stringSchema("Tag identifying the image content, used by skipExisting. May contain template variables."),
+		"secrets":            stringListSchema("Names of build arguments, labels and environment variables whose values are masked in all output"),
+		"sensitiveBuildArgs": stringListSchema("Alias of secrets"),
+		"insecureRegistries": stringListSchema("Registries accessed via plain http"),
+		"envFiles":           stringListSchema("Files with variables for $VAR interpolation in .env syntax, relative to the current directory. Later files override earlier ones."),
+		"envFilePrecedence": {
+			Types:       []string{"string"},
+			Description: "Whether the process environment or env files win if a variable is set in both",
+			Enum:        []string{"environment", "file"},
+		},
+		"skipTests": booleanSchema("Skip the checks of the test section"),
+		"test": {
+			Types:       []string{"array"},
+			Description: "Checks run against the built image before it is pushed. Each check sets exactly one of command, file, exposedPorts, nonRoot or labels.",
+			Items: objectSchema("Check", map[string]*Schema{
+				"name":         stringSchema("Name of the check in the output and the JUnit XML"),
+				"command":      stringListSchema("Run the image with this command, without shell. The first item replaces the entrypoint of the image."),
+				"exitCode":     integerSchema("Expected exit code of the command. Defaults to 0."),
+				"stdout":       stringSchema("Regular expression the standard output of the command must match"),
+				"file":         stringSchema("Path which must exist in the image"),
+				"exposedPorts": stringListSchema("Ports the image must expose, such as 8080 or 53/udp"),
+				"nonRoot":      booleanSchema("Whether the image must run as a user other than root"),
+				"labels":       stringListSchema("Names of labels the image must have"),
+			}),
+		},
+		"watchPaths": stringListSchema("Additional paths whose changes trigger a rebuild of the image given by CONTEXT_DIR. Relative paths are relative to the configuration file setting them."),
+		"images": {
+			Types:       []string{"array"},
+			Description: "Images built when no CONTEXT_DIR is given",
+			Items: &Schema{
+				Types:    []string{"object"},
+				Required: []string{"context"},
+				Properties: map[string]*Schema{
+					"name":       stringSchema("Image name. Defaults to the name of the context directory."),
+					"context":    stringSchema("Context directory. Relative paths are relative to the configuration file declaring the image."),
+					"dockerfile": stringSchema("Path to Dockerfile relative to the context directory"),
+					"watchPaths": stringListSchema("Additional paths whose changes trigger a rebuild. Relative paths are relative to the configuration file declaring the image."),
+					"dependsOn":  stringListSchema("Names of images this image is based on"),
+				},
+			},
+		},
+	}
+}
+
+// RootSchema returns the schema of the configuration file
+func RootSchema() *Schema {
+	presetProperties := settingsProperties()
+	presetProperties["extends"] = &Schema{
+		Types:       []string{"string", "array"},
+		Description: "Presets applied before this preset",
+		Items:       &Schema{Types: []string{"string"}},
+	}
+	presetProperties["extraTags"] = stringListSchema("Tags appended to the tags")
+	presetProperties["extraBuildArgs"] = keyValueListSchema("Build arguments appended to the build arguments")
+	presetProperties["extraLabels"] = stringMapSchema("Labels merged into the labels")
+
+	rootProperties := settingsProperties()
+	rootProperties["rules"] = &Schema{
+		Types:       []string{"array"},
+		Description: "Rules changing tags, presets and the push policy depending on the build, evaluated in order",
+		Items: objectSchema("Rule", map[string]*Schema{
+			"name": stringSchema("Name of the rule, reported by config show"),
+			"when": objectSchema("Conditions which must all match. Patterns are globs or regular expressions enclosed in slashes.", map[string]*Schema{
+				"branch": {
+					Types:       []string{"string", "array"},
+					Description: "Pattern or list of patterns matching the git branch",
+					Items:       &Schema{Types: []string{"string"}},
+				},
+				"tag": {
+					Types:       []string{"boolean", "string"},
+					Description: "Whether a git tag points at the current commit, or a pattern matching the tag",
+				},
+				"dirty": booleanSchema("Whether tracked files have uncommitted changes. Untracked files are ignored."),
+				"env":   stringMapSchema("Patterns matching the values of environment variables"),
+			}),
+			"addTags":    stringListSchema("Tags appended to the tags"),
+			"removeTags": stringListSchema("Tags removed from the tags"),
+			"push":       booleanSchema("Push images after building. Disables the push command if false."),
+			"presets":    stringListSchema("Presets to apply"),
+		}),
+	}
+	rootProperties["include"] = &Schema{
+		Types:       []string{"string", "array"},
+		Description: "Configuration files merged before this file. Relative paths are relative to this file.",
+		Items: &Schema{
+			Types:       []string{"string", "object"},
+			Description: "Path of a file, or a file pinned to a revision of a local git clone",
+			Required:    []string{"path"},
+			Properties: map[string]*Schema{
+				"path":     stringSchema("Path of the file, relative to the repository root if repo is given"),
+				"repo":     stringSchema("Path of a local git clone, relative to this file"),
+				"revision": stringSchema("Git revision the file is read from, such as a tag or commit hash"),
+			},
+		},
+	}
+	rootProperties["presets"] = &Schema{
+		Types:                []string{"object"},
+		Description:          "Named presets selected via --preset",
+		AdditionalProperties: objectSchema("Preset", presetProperties),
+	}
+
+	return objectSchema("draide configuration", rootProperties)
+}
+
+// JSONSchema converts the schema into a JSON Schema document
+func (s *Schema) JSONSchema() map[string]interface{} {
+	doc := s.jsonSchema()
+	doc["$schema"] = "http://json-schema.org/draft-07/schema#"
+	doc["title"] = s.Description
+	delete(doc, "description")
+	return doc
+}
+
+func (s *Schema) jsonSchema() map[string]interface{} {
+	doc := map[string]interface{}{}
+
+	if len(s.Types) == 1 {
+		doc["type"] = s.Types[0]
+	} else {
+		doc["type"] = s.Types
+	}
+	if s.Description != "" {
+		doc["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		doc["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		doc["items"] = s.Items.jsonSchema()
+	}
+	if len(s.Required) > 0 {
+		doc["required"] = s.Required
+	}
+	if s.Properties != nil {
+		properties := map[string]interface{}{}
+		for k, v := range s.Properties {
+			properties[k] = v.jsonSchema()
+		}
+		doc["properties"] = properties
+		if s.AdditionalProperties == nil {
+			doc["additionalProperties"] = false
+		}
+	}
+	if s.AdditionalProperties != nil {
+		doc["additionalProperties"] = s.AdditionalProperties.jsonSchema()
+	}
+
+	return doc
+}
+
+// propertyNames returns the known keys of an object schema in alphabetical order
+func (s *Schema) propertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Contribution records a value a configuration layer contributed to a setting
+type Contribution struct {
+	// Source names the layer, e.g. "config file /path/.draide.yaml", "preset ci" or "flag --tag"
+	Source string
+	// Mode describes how the value was merged: set, append or merge
+	Mode  string
+	Value interface{}
+}
+
+// envPrefix is the prefix of environment variables overriding settings
+const envPrefix = "DRAIDE"
+
+var contributions = map[string][]Contribution{}
+
+var flagBindings = map[string]*pflag.Flag{}
+var envBindings = map[string]string{}
+var defaults = map[string]interface{}{}
+
+// BindFlag binds a setting to a command line flag and records the binding for explaining the configuration
+func BindFlag(key string, flag *pflag.Flag) {
+	viper.BindPFlag(key, flag)
+	flagBindings[strings.ToLower(key)] = flag
+}
+
+// BindEnv binds a setting to an environment variable and records the binding for explaining the configuration
+func BindEnv(key string, envVar string) {
+	viper.BindEnv(key, envVar)
+	envBindings[strings.ToLower(key)] = envVar
+}
+
+// SetDefault sets the default value of a setting and records it for explaining the configuration
+func SetDefault(key string, value interface{}) {
+	viper.SetDefault(key, value)
+	defaults[strings.ToLower(key)] = value
+}
+
+// RecordDefaults records the default values of all settings, either set explicitly or given by the bound flag
+func RecordDefaults() {
+	for key, flag := range flagBindings {
+		if _, ok := defaults[key]; !ok {
+			contribute(key, Contribution{Source: "default", Mode: "set", Value: flag.DefValue})
+		}
+	}
+	for key, value := range defaults {
+		contribute(key, Contribution{Source: "default", Mode: "set", Value: value})
+	}
+}
+
+// RecordLayer records the settings contributed by a configuration layer
+func RecordLayer(source string, mode string, settings map[string]interface{}) {
+	for key, value := range flatten("", settings) {
+		contribute(key, Contribution{Source: source, Mode: mode, Value: value})
+	}
+}
+
+// RecordOverrides records the settings set by environment variables and command line flags.
+// It must be called after all other layers, as those take precedence.
+func RecordOverrides() {
+	keys := map[string]bool{}
+	for _, key := range viper.AllKeys() {
+		keys[key] = true
+	}
+	for key := range envBindings {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		if envVar, ok := envBindings[key]; ok {
+			if value, ok := os.LookupEnv(envVar); ok {
+				contribute(key, Contribution{Source: "environment " + envVar, Mode: "set", Value: value})
+				continue
+			}
+		}
+		envVar := envPrefix + "_" + strings.ToUpper(key)
+		if value, ok := os.LookupEnv(envVar); ok {
+			contribute(key, Contribution{Source: "environment " + envVar, Mode: "set", Value: value})
+		}
+	}
+
+	for key, flag := range flagBindings {
+		if flag.Changed {
+			contribute(key, Contribution{Source: "flag --" + flag.Name, Mode: "set", Value: flag.Value.String()})
+		}
+	}
+}
+
+// setByFlagOrEnv reports whether a setting was given via a command line flag or an environment variable
+func setByFlagOrEnv(key string) bool {
+	key = strings.ToLower(key)
+	if flag, ok := flagBindings[key]; ok && flag.Changed {
+		return true
+	}
+	if envVar, ok := envBindings[key]; ok {
+		if _, ok := os.LookupEnv(envVar); ok {
+			return true
+		}
+	}
+	_, ok := os.LookupEnv(envPrefix + "_" + strings.ToUpper(key))
+	return ok
+}
+
+// Explain returns every contribution to a setting in the order the layers were applied
+func Explain(key string) []Contribution {
+	key = strings.ToLower(key)
+
+	result := append([]Contribution{}, contributions[key]...)
+	// contributions to nested settings, e.g. labels.team when explaining labels
+	nested := []string{}
+	for k := range contributions {
+		if strings.HasPrefix(k, key+".") {
+			nested = append(nested, k)
+		}
+	}
+	sort.Strings(nested)
+	for _, k := range nested {
+		for _, c := range contributions[k] {
+			c.Source = fmt.Sprintf("%s (%s)", c.Source, k)
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+func contribute(key string, c Contribution) {
+	key = strings.ToLower(key)
+	contributions[key] = append(contributions[key], c)
+}
+
+// flatten converts nested settings into dot separated keys
+func flatten(prefix string, settings map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+
+	for k, v := range settings {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch nested := v.(type) {
+		case map[string]interface{}:
+			for nk, nv := range flatten(key, nested) {
+				flat[nk] = nv
+			}
+		case map[interface{}]interface{}:
+			converted := map[string]interface{}{}
+			for nk, nv := range nested {
+				converted[fmt.Sprint(nk)] = nv
+			}
+			for nk, nv := range flatten(key, converted) {
+				flat[nk] = nv
+			}
+		default:
+			flat[key] = v
+		}
+	}
+
+	return flat
+}
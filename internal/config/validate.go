@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a validation issue
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found while validating a configuration file
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Severity, i.Message)
+}
+
+// ValidationSupported reports whether a configuration file format supports schema validation
+func ValidationSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// ValidateContent checks the content of a YAML or JSON configuration file against the configuration schema.
+// Unknown keys are reported as warnings, all other issues as errors. The path is used for reporting only.
+func ValidateContent(path string, content []byte) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return []Issue{parseIssue(path, err)}
+	}
+	if len(doc.Content) == 0 {
+		return []Issue{}
+	}
+
+	v := validator{file: path, issues: []Issue{}}
+	v.validate(doc.Content[0], RootSchema(), "")
+	return v.issues
+}
+
+// parseIssue converts a YAML syntax error into an issue, the YAML parser reports lines only
+func parseIssue(path string, err error) Issue {
+	issue := Issue{File: path, Line: 1, Column: 1, Severity: SeverityError, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+	var line int
+	if _, scanErr := fmt.Sscanf(issue.Message, "line %d:", &line); scanErr == nil {
+		issue.Line = line
+		issue.Message = strings.TrimSpace(strings.SplitN(issue.Message, ":", 2)[1])
+	}
+	return issue
+}
+
+type validator struct {
+	file   string
+	issues []Issue
+}
+
+func (v *validator) report(node *yaml.Node, severity string, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		File:     v.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(node *yaml.Node, schema *Schema, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	nodeType := yamlType(node)
+	if !typeAllowed(schema.Types, nodeType) {
+		v.report(node, SeverityError, "%s must be %s, found %s", displayPath(path), strings.Join(schema.Types, " or "), nodeType)
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateObject(node, schema, path)
+	case yaml.SequenceNode:
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				v.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case yaml.ScalarNode:
+		if len(schema.Enum) > 0 && !contains(schema.Enum, node.Value) {
+			v.report(node, SeverityError, "%s must be one of %s, found %q", displayPath(path), strings.Join(schema.Enum, ", "), node.Value)
+		}
+	}
+}
+
+func (v *validator) validateObject(node *yaml.Node, schema *Schema, path string) {
+	seen := map[string]bool{}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		childPath := strings.TrimPrefix(path+"."+key, ".")
+
+		// settings are case-insensitive, like viper treats them
+		if seen[strings.ToLower(key)] {
+			v.report(keyNode, SeverityError, "duplicate key %q", childPath)
+		}
+		seen[strings.ToLower(key)] = true
+
+		if property := schema.property(key); property != nil {
+			v.validate(valueNode, property, childPath)
+		} else if schema.AdditionalProperties != nil {
+			v.validate(valueNode, schema.AdditionalProperties, childPath)
+		} else {
+			message := fmt.Sprintf("unknown key %q", childPath)
+			if suggestion := suggest(key, schema.propertyNames()); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			v.report(keyNode, SeverityWarning, message)
+		}
+	}
+
+	for _, required := range schema.Required {
+		if !seen[strings.ToLower(required)] {
+			v.report(node, SeverityError, "%s is missing required key %q", displayPath(path), required)
+		}
+	}
+}
+
+// property looks up a known key case-insensitively
+func (s *Schema) property(key string) *Schema {
+	for k, p := range s.Properties {
+		if strings.EqualFold(k, key) {
+			return p
+		}
+	}
+	return nil
+}
+
+func yamlType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+
+	switch node.Tag {
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func typeAllowed(allowed []string, actual string) bool {
+	for _, t := range allowed {
+		// scalars are converted to strings when read, so any scalar is a valid string
+		if t == actual || actual == "null" || (t == "string" && (actual == "boolean" || actual == "integer" || actual == "number")) {
+			return true
+		}
+	}
+	return false
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "configuration"
+	}
+	return path
+}
+
+// suggest returns the candidate closest to a misspelled key, if any is close enough
+func suggest(key string, candidates []string) string {
+	best := ""
+	bestDistance := len(key)/3 + 2
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(key), strings.ToLower(c)); d < bestDistance {
+			best = c
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
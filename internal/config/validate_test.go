@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "valid",
+			content: "registry: r\npush: true\ntags: [latest]\nlabels:\n  team: core\n",
+			want:    []string{},
+		},
+		{
+			name:    "empty",
+			content: "",
+			want:    []string{},
+		},
+		{
+			name:    "misspelled key",
+			content: "registy: r\n",
+			want:    []string{`f.yaml:1:1: warning: unknown key "registy", did you mean "registry"?`},
+		},
+		{
+			name:    "unknown key without suggestion",
+			content: "push: true\ncompletelyUnrelated: 1\n",
+			want:    []string{`f.yaml:2:1: warning: unknown key "completelyUnrelated"`},
+		},
+		{
+			name:    "misspelled nested key",
+			content: "run:\n  netwrk: host\n",
+			want:    []string{`f.yaml:2:3: warning: unknown key "run.netwrk", did you mean "network"?`},
+		},
+		{
+			name:    "wrong type",
+			content: "tags: latest\n",
+			want:    []string{"f.yaml:1:7: error: tags must be array, found string"},
+		},
+		{
+			name:    "scalars are strings",
+			content: "registry: 1\nnamespace: true\n",
+			want:    []string{},
+		},
+		{
+			name:    "enum",
+			content: "color: sometimes\n",
+			want:    []string{`f.yaml:1:8: error: color must be one of auto, always, never, found "sometimes"`},
+		},
+		{
+			name:    "duplicate key in another case",
+			content: "push: true\nPush: false\n",
+			want:    []string{`f.yaml:2:1: error: duplicate key "Push"`},
+		},
+		{
+			name:    "missing required key",
+			content: "buildArgs:\n  - value: x\n",
+			want:    []string{`f.yaml:2:5: error: buildArgs[0] is missing required key "key"`},
+		},
+		{
+			name:    "syntax error",
+			content: "push: true\n  registry: r\n",
+			want:    []string{"f.yaml:2:1: error: mapping values are not allowed in this context"},
+		},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, issue := range ValidateContent("f.yaml", []byte(tt.content)) {
+			got = append(got, issue.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ValidateContent() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"registry", "namespace", "tags", "push"}
+	tests := map[string]string{
+		"registy":   "registry",
+		"REGISTRY":  "registry",
+		"namespce":  "namespace",
+		"tag":       "tags",
+		"pull":      "push",
+		"unrelated": "",
+		"x":         "",
+	}
+	for key, want := range tests {
+		if got := suggest(key, candidates); got != want {
+			t.Errorf("suggest(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestValidationSupported(t *testing.T) {
+	tests := map[string]bool{
+		".draide.yaml": true,
+		".draide.YML":  true,
+		".draide.json": true,
+		".draide.toml": false,
+		".draide":      false,
+	}
+	for path, want := range tests {
+		if got := ValidationSupported(path); got != want {
+			t.Errorf("ValidationSupported(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
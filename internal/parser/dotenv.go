@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+var dotenvUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\$`, `$`)
+
+// envFileVars holds the variables loaded from .env files. They are never exported to the process environment.
+var envFileVars = map[string]string{}
+
+// ParseDotenv parses the content of a .env file.
+//
+// Lines have the form KEY=VALUE, optionally prefixed with `export`. Blank lines and lines starting with # are ignored.
+// Values may be single quoted (taken literally) or double quoted (supporting \n, \t, \", \\ and \$ escapes),
+// quoted values may span multiple lines. Unquoted values end at an inline comment starting with " #".
+func ParseDotenv(content string) (map[string]string, error) {
+	vars := map[string]string{}
+	lines := strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export"):])
+		}
+
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		key := strings.TrimSpace(line[:separator])
+		if !dotenvKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, key)
+		}
+		value := strings.TrimSpace(line[separator+1:])
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			raw := value[1:]
+			for {
+				if end := closingQuote(raw, quote); end >= 0 {
+					if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+						return nil, fmt.Errorf("line %d: unexpected characters after quoted value", i+1)
+					}
+					raw = raw[:end]
+					break
+				}
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+				}
+				raw += "\n" + lines[i]
+			}
+			if quote == '"' {
+				raw = dotenvUnescaper.Replace(raw)
+			}
+			value = raw
+		} else if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote ending a quoted value, skipping escaped quotes in double quoted values
+func closingQuote(str string, quote byte) int {
+	for i := 0; i < len(str); i++ {
+		if quote == '"' && str[i] == '\\' {
+			i++
+			continue
+		}
+		if str[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// LoadEnvFiles reads variables from .env files, later files overriding earlier ones.
+// The files are requested explicitly, so a missing file is an error rather than silently leaving variables unset.
+func LoadEnvFiles(paths []string) error {
+	for _, path := range paths {
+		path, err := homedir.Expand(path)
+		if err != nil {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("env file %s does not exist", path)
+		}
+		if err != nil {
+			return err
+		}
+
+		vars, err := ParseDotenv(string(content))
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for k, v := range vars {
+			if IsSensitiveKey(k) {
+				ui.AddSecret(v)
+			}
+			envFileVars[k] = v
+		}
+		ui.Log("Loaded %d variables from env file %s", len(vars), path)
+	}
+
+	return nil
+}
+
+// LookupEnv returns the value of a variable from the process environment or the loaded .env files.
+// If a variable is set in both, the process environment wins unless `envFilePrecedence` is set to "file".
+func LookupEnv(name string) (string, bool) {
+	envValue, inEnv := os.LookupEnv(name)
+	fileValue, inFile := envFileVars[name]
+
+	if inFile && (!inEnv || viper.GetString("envFilePrecedence") == "file") {
+		return fileValue, true
+	}
+	return envValue, inEnv
+}
@@ -1,22 +1,36 @@
 package parser
 
 import (
-	"os"
 	"regexp"
 
+	"github.com/marcelriegr/draide/pkg/failure"
 	"github.com/marcelriegr/draide/pkg/ui"
 )
 
-// Env replaces all variable starting with a $ (dollar sign) or # (number sign) character inside a string with the corresponding environment variable
-func Env(str string) string {
-	pattern := regexp.MustCompile(`[\$#]\w+`)
-	return pattern.ReplaceAllStringFunc(str, func(envVar string) string {
-		val := os.Getenv(envVar[1:])
+// envVarPattern matches $VAR and #VAR, or ${VAR} and #{VAR} for names containing dots
+var envVarPattern = regexp.MustCompile(`[\$#](?:\{([A-Za-z_][A-Za-z0-9_.]*)\}|(\w+))`)
+
+// Env replaces all variable starting with a $ (dollar sign) or # (number sign) character inside a string with the corresponding environment variable.
+// Variables loaded from .env files are resolved as well, see LookupEnv. Unset or empty variables are a config-invalid failure.
+func Env(str string) (string, error) {
+	var err error
+	result := envVarPattern.ReplaceAllStringFunc(str, func(envVar string) string {
+		match := envVarPattern.FindStringSubmatch(envVar)
+		name := match[1] + match[2]
+		val, _ := LookupEnv(name)
 
 		if val == "" {
-			ui.ErrorAndExit(1, "Cannot resolve environment variable %s", envVar)
+			if err == nil {
+				err = failure.New(failure.ConfigInvalid, "Cannot resolve environment variable %s", envVar)
+			}
+			return envVar
+		}
+
+		if IsSensitiveKey(name) {
+			ui.AddSecret(val)
 		}
 
 		return val
 	})
+	return result, err
 }
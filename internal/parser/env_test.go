@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+)
+
+func withEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "draide-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, ".env")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := envFileVars
+	envFileVars = map[string]string{}
+	t.Cleanup(func() { envFileVars = previous })
+	return path
+}
+
+func TestEnvBraces(t *testing.T) {
+	path := withEnvFile(t, "app.version=1.2.3\nMAJOR=1\nMINOR=2\n")
+	if err := LoadEnvFiles([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"v${app.version}":   "v1.2.3",
+		"#{app.version}-rc": "1.2.3-rc",
+		"$MAJOR.$MINOR":     "1.2",
+		"${MAJOR}0":         "10",
+	}
+	for template, want := range tests {
+		if got, err := Env(template); err != nil || got != want {
+			t.Errorf("Env(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestLoadEnvFilesMissing(t *testing.T) {
+	path := withEnvFile(t, "A=1\n")
+
+	err := LoadEnvFiles([]string{path, path + ".typo"})
+	if err == nil || !strings.Contains(err.Error(), ".env.typo") {
+		t.Errorf("LoadEnvFiles() = %v, want an error naming the missing file", err)
+	}
+}
+
+func TestEnvUnresolved(t *testing.T) {
+	withEnvFile(t, "")
+
+	_, err := Env("$DRAIDE_TEST_UNSET")
+	if failure.ClassOf(err) != failure.ConfigInvalid {
+		t.Errorf("Env() error = %v, want a config-invalid failure", err)
+	}
+}
@@ -6,8 +6,8 @@ import (
 
 var removeTag = "<REMOVE>"
 
-// RepositoryName tbd
-func RepositoryName(repositoryNameTemplate string, tagTemplates []string, templateVars TemplateVars) []string {
+// RepositoryName resolves the repository name template together with each tag template into an image reference
+func RepositoryName(repositoryNameTemplate string, tagTemplates []string, templateVars TemplateVars) ([]string, error) {
 	names := make([]string, len(tagTemplates))
 
 	templateVarsWithRemoveTag := make(map[string]string)
@@ -20,11 +20,17 @@ func RepositoryName(repositoryNameTemplate string, tagTemplates []string, templa
 	}
 
 	for i, tagTemplate := range tagTemplates {
-		name := regexp.MustCompile(removeTag+`\/`).ReplaceAllString(Template(repositoryNameTemplate, templateVarsWithRemoveTag), "")
-		tag := Template(tagTemplate, templateVars)
+		name, err := Template(repositoryNameTemplate, templateVarsWithRemoveTag)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := Template(tagTemplate, templateVars)
+		if err != nil {
+			return nil, err
+		}
 
-		names[i] = name + ":" + tag
+		names[i] = regexp.MustCompile(removeTag+`\/`).ReplaceAllString(name, "") + ":" + tag
 	}
 
-	return names
+	return names, nil
 }
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// sensitiveKeyPattern matches keys containing a credential word delimited by _, - or ., such as API_KEY or db.password
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(^|[_.-])(TOKEN|PASSWORD|PASSWD|SECRET|KEY)([_.-]|$)`)
+
+// caseBoundaryPatterns match the word boundaries of camelCase keys, such as npmToken or APIKey
+var caseBoundaryPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`([a-z0-9])([A-Z])`),
+	regexp.MustCompile(`([A-Z])([A-Z][a-z])`),
+}
+
+// IsSensitiveKey reports whether values stored under the given key (build argument, label or environment variable) must be treated as secret.
+// A key is sensitive if it is listed in the `secrets` or `sensitiveBuildArgs` configuration or if its name looks like a credential,
+// with words delimited by _, -, . or a change of case.
+func IsSensitiveKey(key string) bool {
+	configured := append(viper.GetStringSlice("secrets"), viper.GetStringSlice("sensitiveBuildArgs")...)
+	for _, k := range configured {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+
+	for _, p := range caseBoundaryPatterns {
+		key = p.ReplaceAllString(key, "${1}_${2}")
+	}
+	return sensitiveKeyPattern.MatchString(key)
+}
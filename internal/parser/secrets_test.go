@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	viper.Reset()
+	viper.Set("secrets", []string{"DATABASE_URL"})
+	defer viper.Reset()
+
+	tests := map[string]bool{
+		"API_KEY":         true,
+		"GITHUB_TOKEN":    true,
+		"token":           true,
+		"db.password":     true,
+		"npm-secret-file": true,
+		"MYSQL_PASSWD":    true,
+		"database_url":    true,
+		"MONKEY":          false,
+		"KEYBOARD_LAYOUT": false,
+		"TOKENIZER":       false,
+		"VERSION":         false,
+		"npmToken":        true,
+		"apiKey":          true,
+		"APIKey":          true,
+		"dbPassword":      true,
+		"clientSecretRef": true,
+		"keyboardLayout":  false,
+		"monkeyPatch":     false,
+		"tokenizerName":   false,
+	}
+	for key, want := range tests {
+		if got := IsSensitiveKey(key); got != want {
+			t.Errorf("IsSensitiveKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -2,20 +2,24 @@ package parser
 
 import (
 	"io"
+	"regexp"
+	"strings"
 
+	"github.com/marcelriegr/draide/pkg/failure"
 	"github.com/marcelriegr/draide/pkg/gittools"
-	"github.com/marcelriegr/draide/pkg/ui"
 
 	"github.com/spf13/viper"
 	"github.com/valyala/fasttemplate"
 )
 
+var semverPattern = regexp.MustCompile(`^v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)(?:\+[0-9A-Za-z.-]+)?$`)
+
 // TemplateVars tbd
 type TemplateVars map[string]string
 
 // GenerateTemplateVarsOptions tbd
 type GenerateTemplateVarsOptions struct {
-	contextDir string
+	ContextDir string
 }
 
 // GenerateTemplateVars tbd
@@ -26,48 +30,68 @@ func GenerateTemplateVars(opts GenerateTemplateVarsOptions) TemplateVars {
 		"NAMESPACE":  viper.GetString("namespace"),
 	}
 
-	if opts.contextDir == "" {
-		opts.contextDir = "."
+	if opts.ContextDir == "" {
+		opts.ContextDir = "."
 	}
 
-	repoDetails, _ := gittools.GetRepoDetails(opts.contextDir)
+	repoDetails, _ := gittools.GetRepoDetails(opts.ContextDir)
 	if repoDetails != nil {
 		vars["BRANCH"] = repoDetails.Branch
 		vars["COMMIT_HASH"] = repoDetails.CommitHash
+		vars["SHORT_COMMIT_HASH"] = repoDetails.CommitHash[:7]
+		vars["TAG"] = repoDetails.Tag
+		vars["SEMVER"] = Semver(repoDetails.Tag)
 	}
 
 	return vars
 }
 
-// Template tbd
-func Template(template string, templateVars TemplateVars) string {
+// Template resolves environment and template variables inside a string.
+// Unknown or unresolvable variables are a config-invalid failure.
+func Template(template string, templateVars TemplateVars) (string, error) {
 	// Interpolate environment variables
-	template = Env(template)
+	template, err := Env(template)
+	if err != nil {
+		return "", err
+	}
 
 	// Parse template
 	t, err := fasttemplate.NewTemplate(template, "%", "%")
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed parsing template %s", template)
+		return "", failure.New(failure.ConfigInvalid, "Failed parsing template %s: %v", template, err)
 	}
 
 	// Interpolate template variables
-	return t.ExecuteFuncString(func(w io.Writer, templateVar string) (int, error) {
+	return t.ExecuteFuncStringWithErr(func(w io.Writer, templateVar string) (int, error) {
 		val, validKey := templateVars[templateVar]
 
 		if !validKey {
-			ui.ErrorAndExit(1, "Unrecognized template variable: %s", templateVar)
+			return 0, failure.New(failure.ConfigInvalid, "Unrecognized template variable: %s", templateVar)
 		}
 
 		if val == "" {
 			switch templateVar {
 			case "BRANCH":
-			case "COMMIT_HASH":
-				ui.ErrorAndExit(1, "Cannot resolve %%%s%% on a non git repository", templateVar)
+			case "COMMIT_HASH", "SHORT_COMMIT_HASH":
+				return 0, failure.New(failure.ConfigInvalid, "Cannot resolve %%%s%% on a non git repository", templateVar)
+			case "TAG":
+				return 0, failure.New(failure.ConfigInvalid, "Cannot resolve %%TAG%%. No git tag points at the current commit.")
+			case "SEMVER":
+				return 0, failure.New(failure.ConfigInvalid, "Cannot resolve %%SEMVER%%. No semantic version tag points at the current commit.")
 			}
-			ui.ErrorAndExit(1, "Cannot resolve template variable %s", templateVar)
+			return 0, failure.New(failure.ConfigInvalid, "Cannot resolve template variable %s", templateVar)
 		}
 
 		return w.Write([]byte(val))
 	})
 }
+
+// Semver returns the semantic version of a git tag such as v1.2.3 without the v prefix and build metadata, which is not allowed in image tags.
+// It returns an empty string if the tag is no semantic version.
+func Semver(tag string) string {
+	match := semverPattern.FindStringSubmatch(strings.TrimSpace(tag))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+)
+
+func TestRepositoryName(t *testing.T) {
+	vars := TemplateVars{"REGISTRY": "", "NAMESPACE": "team", "IMAGE_NAME": "app", "TAG": "v1.2.3", "SEMVER": "1.2.3"}
+
+	names, err := RepositoryName("%REGISTRY%/%NAMESPACE%/%IMAGE_NAME%", []string{"%SEMVER%", "latest"}, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "team/app:1.2.3" || names[1] != "team/app:latest" {
+		t.Errorf("RepositoryName() = %v", names)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	vars := TemplateVars{"IMAGE_NAME": "app", "TAG": "", "SEMVER": ""}
+
+	for _, template := range []string{"%UNKNOWN%", "%TAG%", "%SEMVER%", "$DRAIDE_TEST_UNSET"} {
+		_, err := Template(template, vars)
+		if failure.ClassOf(err) != failure.ConfigInvalid {
+			t.Errorf("Template(%q) error = %v, want a config-invalid failure", template, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package report
+
+import "strings"
+
+// CacheStats summarizes how many build steps were served from the build cache
+type CacheStats struct {
+	CachedSteps    int `json:"cachedSteps"`
+	CacheableSteps int `json:"cacheableSteps"`
+	// FirstMiss is the first cacheable step which was not cached. The daemon rebuilds all following steps.
+	FirstMiss *Step `json:"firstMiss,omitempty"`
+}
+
+// NewCacheStats summarizes the cache usage of build steps. FROM steps are not cacheable and not counted.
+func NewCacheStats(steps []*Step) *CacheStats {
+	stats := &CacheStats{}
+	for _, step := range steps {
+		if strings.HasPrefix(strings.ToUpper(step.Instruction), "FROM ") {
+			continue
+		}
+		stats.CacheableSteps++
+		if step.Cached {
+			stats.CachedSteps++
+		} else if stats.FirstMiss == nil {
+			stats.FirstMiss = step
+		}
+	}
+	return stats
+}
+
+// Ratio returns the share of cacheable steps served from the cache, 1 if there are no cacheable steps
+func (s *CacheStats) Ratio() float64 {
+	if s.CacheableSteps == 0 {
+		return 1
+	}
+	return float64(s.CachedSteps) / float64(s.CacheableSteps)
+}
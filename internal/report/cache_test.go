@@ -0,0 +1,37 @@
+package report
+
+import "testing"
+
+func TestNewCacheStats(t *testing.T) {
+	from := &Step{Number: 1, Instruction: "FROM alpine"}
+	copyCached := &Step{Number: 2, Instruction: "COPY go.mod ./", Cached: true}
+	run := &Step{Number: 3, Instruction: "RUN go build", Cached: false}
+	cmd := &Step{Number: 4, Instruction: "CMD [\"/app\"]", Cached: false}
+	cmdCached := &Step{Number: 4, Instruction: "CMD [\"/app\"]", Cached: true}
+	lowerFrom := &Step{Number: 5, Instruction: "from scratch"}
+
+	tests := []struct {
+		name      string
+		steps     []*Step
+		cached    int
+		cacheable int
+		firstMiss *Step
+		ratio     float64
+	}{
+		{name: "no steps", steps: []*Step{}, ratio: 1},
+		{name: "only FROM", steps: []*Step{from, lowerFrom}, ratio: 1},
+		{name: "all cached", steps: []*Step{from, copyCached, cmdCached}, cached: 2, cacheable: 2, ratio: 1},
+		{name: "cache busted", steps: []*Step{from, copyCached, run, cmd}, cached: 1, cacheable: 3, firstMiss: run, ratio: 1.0 / 3},
+		{name: "nothing cached", steps: []*Step{from, run, cmd}, cacheable: 2, firstMiss: run, ratio: 0},
+		{name: "multi-stage", steps: []*Step{from, run, lowerFrom, copyCached}, cached: 1, cacheable: 2, firstMiss: run, ratio: 0.5},
+	}
+	for _, tt := range tests {
+		stats := NewCacheStats(tt.steps)
+		if stats.CachedSteps != tt.cached || stats.CacheableSteps != tt.cacheable || stats.FirstMiss != tt.firstMiss {
+			t.Errorf("%s: NewCacheStats() = %+v, want %d of %d cached, first miss %+v", tt.name, stats, tt.cached, tt.cacheable, tt.firstMiss)
+		}
+		if got := stats.Ratio(); got != tt.ratio {
+			t.Errorf("%s: Ratio() = %v, want %v", tt.name, got, tt.ratio)
+		}
+	}
+}
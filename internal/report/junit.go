@@ -0,0 +1,93 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// JUnit renders the test results of the report as JUnit XML, with a test suite per image. Secret values are redacted.
+func (r *Report) JUnit() ([]byte, error) {
+	suites := &junitTestSuites{Name: "draide"}
+	seconds := 0.0
+	for _, image := range r.Images {
+		if len(image.Tests) == 0 {
+			continue
+		}
+		suite := &junitTestSuite{Name: ui.Redact(image.Name)}
+		suiteSeconds := 0.0
+		for _, test := range image.Tests {
+			testCase := &junitTestCase{
+				Name:      ui.Redact(test.Name),
+				Classname: ui.Redact(image.Name) + "." + test.Kind,
+				Time:      formatJUnitSeconds(test.Seconds),
+				SystemOut: ui.Redact(test.Output),
+			}
+			if !test.Passed {
+				testCase.Failure = &junitFailure{Message: ui.Redact(test.Message), Type: test.Kind}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+			suite.Tests++
+			suiteSeconds += test.Seconds
+		}
+		suite.Time = formatJUnitSeconds(suiteSeconds)
+		suites.Suites = append(suites.Suites, suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		seconds += suiteSeconds
+	}
+	suites.Time = formatJUnitSeconds(seconds)
+
+	content, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// WriteJUnit stores the test results of the report as JUnit XML file. Secret values are redacted.
+func (r *Report) WriteJUnit(path string) error {
+	content, err := r.JUnit()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+func formatJUnitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metric is a metric family in OpenMetrics text format
+type metric struct {
+	name    string
+	unit    string
+	help    string
+	samples []string
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(ui.Redact(labels[i+1]))))
+	}
+	m.samples = append(m.samples, fmt.Sprintf("%s{%s} %g", m.name, strings.Join(pairs, ","), value))
+}
+
+// OpenMetrics renders the timings of the report in OpenMetrics text format
+func (r *Report) OpenMetrics() string {
+	contextSize := &metric{name: "draide_context_size_bytes", unit: "bytes", help: "Size of the packed build context"}
+	packing := &metric{name: "draide_context_packing_seconds", unit: "seconds", help: "Time spent packing the build context"}
+	upload := &metric{name: "draide_context_upload_seconds", unit: "seconds", help: "Time spent uploading the build context"}
+	build := &metric{name: "draide_build_seconds", unit: "seconds", help: "Time the daemon spent building"}
+	steps := &metric{name: "draide_build_step_seconds", unit: "seconds", help: "Time spent per build step"}
+	cache := &metric{name: "draide_build_cache_hit_ratio", unit: "ratio", help: "Share of cacheable build steps served from the build cache"}
+	pushes := &metric{name: "draide_push_seconds", unit: "seconds", help: "Time spent pushing a tag, including retries"}
+
+	for _, image := range r.Images {
+		if image.Timings == nil {
+			continue
+		}
+		t := image.Timings
+		if t.ContextSize > 0 {
+			contextSize.add(float64(t.ContextSize), "image", image.Name)
+			packing.add(t.PackingSeconds, "image", image.Name)
+			upload.add(t.UploadSeconds, "image", image.Name)
+			build.add(t.BuildSeconds, "image", image.Name)
+		}
+		for _, step := range image.Steps {
+			steps.add(step.Seconds, "image", image.Name, "step", fmt.Sprint(step.Number), "instruction", step.Instruction)
+		}
+		if image.Cache != nil && image.Cache.CacheableSteps > 0 {
+			cache.add(image.Cache.Ratio(), "image", image.Name)
+		}
+		for _, push := range t.Pushes {
+			pushes.add(push.Seconds, "image", image.Name, "tag", push.Tag)
+		}
+	}
+
+	var b strings.Builder
+	for _, m := range []*metric{contextSize, packing, upload, build, steps, cache, pushes} {
+		if len(m.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", m.name)
+		fmt.Fprintf(&b, "# UNIT %s %s\n", m.name, m.unit)
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+		for _, sample := range m.samples {
+			b.WriteString(sample + "\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+// WriteMetrics stores the timings of the report as OpenMetrics text file. Secret values in labels are redacted.
+func (r *Report) WriteMetrics(path string) error {
+	return ioutil.WriteFile(path, []byte(r.OpenMetrics()), 0644)
+}
@@ -0,0 +1,68 @@
+package report
+
+import "testing"
+
+func TestOpenMetrics(t *testing.T) {
+	r := &Report{Images: []*Image{
+		{
+			Name: "app",
+			Timings: &Timings{
+				ContextSize:    2048,
+				PackingSeconds: 0.25,
+				UploadSeconds:  0.5,
+				BuildSeconds:   12,
+				Pushes:         []*PushedTag{{Tag: "registry.example.com/app:1.0", Seconds: 3.5}},
+			},
+			Steps: []*Step{
+				{Number: 1, Instruction: "FROM alpine", Seconds: 0.1},
+				{Number: 2, Instruction: `RUN echo "a\b"` + "\nline", Seconds: 1.5, Cached: true},
+			},
+			Cache: &CacheStats{CachedSteps: 1, CacheableSteps: 2},
+		},
+		// images without timings, such as reused ones, have no samples
+		{Name: "reused", Reused: true},
+	}}
+
+	want := `# TYPE draide_context_size_bytes gauge
+# UNIT draide_context_size_bytes bytes
+# HELP draide_context_size_bytes Size of the packed build context
+draide_context_size_bytes{image="app"} 2048
+# TYPE draide_context_packing_seconds gauge
+# UNIT draide_context_packing_seconds seconds
+# HELP draide_context_packing_seconds Time spent packing the build context
+draide_context_packing_seconds{image="app"} 0.25
+# TYPE draide_context_upload_seconds gauge
+# UNIT draide_context_upload_seconds seconds
+# HELP draide_context_upload_seconds Time spent uploading the build context
+draide_context_upload_seconds{image="app"} 0.5
+# TYPE draide_build_seconds gauge
+# UNIT draide_build_seconds seconds
+# HELP draide_build_seconds Time the daemon spent building
+draide_build_seconds{image="app"} 12
+# TYPE draide_build_step_seconds gauge
+# UNIT draide_build_step_seconds seconds
+# HELP draide_build_step_seconds Time spent per build step
+draide_build_step_seconds{image="app",step="1",instruction="FROM alpine"} 0.1
+draide_build_step_seconds{image="app",step="2",instruction="RUN echo \"a\\b\"\nline"} 1.5
+# TYPE draide_build_cache_hit_ratio gauge
+# UNIT draide_build_cache_hit_ratio ratio
+# HELP draide_build_cache_hit_ratio Share of cacheable build steps served from the build cache
+draide_build_cache_hit_ratio{image="app"} 0.5
+# TYPE draide_push_seconds gauge
+# UNIT draide_push_seconds seconds
+# HELP draide_push_seconds Time spent pushing a tag, including retries
+draide_push_seconds{image="app",tag="registry.example.com/app:1.0"} 3.5
+# EOF
+`
+	if got := r.OpenMetrics(); got != want {
+		t.Errorf("OpenMetrics() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOpenMetricsEmpty(t *testing.T) {
+	r := &Report{Images: []*Image{{Name: "reused", Reused: true}}}
+
+	if got := r.OpenMetrics(); got != "# EOF\n" {
+		t.Errorf("OpenMetrics() = %q, want only the EOF terminator", got)
+	}
+}
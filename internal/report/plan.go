@@ -0,0 +1,139 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+// Plan describes what a run would do, as printed in dry-run mode
+type Plan struct {
+	Command      string          `json:"command"`
+	ConfigFiles  []string        `json:"configFiles"`
+	Presets      []string        `json:"presets"`
+	MatchedRules []string        `json:"matchedRules"`
+	Images       []*PlannedImage `json:"images"`
+}
+
+// PlannedImage describes how an image would be built and where it would be pushed to
+type PlannedImage struct {
+	Name        string            `json:"name"`
+	Context     string            `json:"context,omitempty"`
+	Dockerfile  string            `json:"dockerfile,omitempty"`
+	ContextHash string            `json:"contextHash,omitempty"`
+	BuildArgs   map[string]string `json:"buildArgs,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	NoCache     bool              `json:"noCache,omitempty"`
+	Tags        []string          `json:"tags"`
+	// IdentityTag is checked in the registry before building if skipping existing images is enabled
+	IdentityTag string            `json:"identityTag,omitempty"`
+	Push        bool              `json:"push"`
+	Registries  []PlannedRegistry `json:"registries"`
+	// Tests are the names of the checks run against the built image before pushing
+	Tests []string `json:"tests,omitempty"`
+}
+
+// PlannedRegistry describes a registry an image is pushed to and the credentials used for it
+type PlannedRegistry struct {
+	Registry string `json:"registry"`
+	// Authenticated is set if credentials are used. The username is left out, as plans end up in CI logs.
+	Authenticated bool `json:"authenticated"`
+	// CredentialSource names where the credentials come from, such as "--username flag" or "stored credentials"
+	CredentialSource string `json:"credentialSource"`
+}
+
+// NewPlan creates an empty plan for a command
+func NewPlan(command string) *Plan {
+	return &Plan{
+		Command:      command,
+		ConfigFiles:  []string{},
+		Presets:      []string{},
+		MatchedRules: []string{},
+		Images:       []*PlannedImage{},
+	}
+}
+
+// AddImage appends a planned image to the plan
+func (p *Plan) AddImage(image *PlannedImage) *PlannedImage {
+	p.Images = append(p.Images, image)
+	return image
+}
+
+// JSON renders the plan as JSON. Secret values are redacted.
+func (p *Plan) JSON() (string, error) {
+	content, err := ui.MarshalRedacted(p)
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// Text renders the plan in human readable form. Secret values are redacted.
+func (p *Plan) Text() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Plan for %s\n", p.Command)
+	fmt.Fprintf(&b, "configuration files: %s\n", listOrNone(p.ConfigFiles))
+	fmt.Fprintf(&b, "presets: %s\n", listOrNone(p.Presets))
+	fmt.Fprintf(&b, "matched rules: %s\n", listOrNone(p.MatchedRules))
+
+	for _, image := range p.Images {
+		fmt.Fprintf(&b, "\nimage %s\n", image.Name)
+		if image.Context != "" {
+			fmt.Fprintf(&b, "  context: %s\n", image.Context)
+			fmt.Fprintf(&b, "  dockerfile: %s\n", image.Dockerfile)
+			if image.ContextHash != "" {
+				fmt.Fprintf(&b, "  context hash: %s\n", image.ContextHash)
+			}
+			fmt.Fprintf(&b, "  no-cache: %v\n", image.NoCache)
+			writeMap(&b, "build args", image.BuildArgs)
+			writeMap(&b, "labels", image.Labels)
+			fmt.Fprintf(&b, "  tests: %s\n", listOrNone(image.Tests))
+		}
+		fmt.Fprintf(&b, "  tags:\n")
+		for _, tag := range image.Tags {
+			fmt.Fprintf(&b, "    - %s\n", tag)
+		}
+		if image.IdentityTag != "" {
+			fmt.Fprintf(&b, "  skip if exists: %s\n", image.IdentityTag)
+		}
+		fmt.Fprintf(&b, "  push: %v\n", image.Push)
+		fmt.Fprintf(&b, "  registries:\n")
+		for _, r := range image.Registries {
+			if r.Authenticated {
+				fmt.Fprintf(&b, "    - %s (credentials from %s)\n", r.Registry, r.CredentialSource)
+			} else {
+				fmt.Fprintf(&b, "    - %s (%s)\n", r.Registry, r.CredentialSource)
+			}
+		}
+	}
+
+	return ui.Redact(b.String())
+}
+
+func writeMap(b *strings.Builder, title string, values map[string]string) {
+	if len(values) == 0 {
+		fmt.Fprintf(b, "  %s: <none>\n", title)
+		return
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "  %s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(b, "    %s: %s\n", k, values[k])
+	}
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ", ")
+}
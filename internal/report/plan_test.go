@@ -0,0 +1,27 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanTextCredentials(t *testing.T) {
+	plan := NewPlan("build")
+	plan.AddImage(&PlannedImage{
+		Name: "app",
+		Registries: []PlannedRegistry{
+			{Registry: "registry.example.com", Authenticated: true, CredentialSource: "DRAIDE_USERNAME environment variable"},
+			{Registry: "docker.io", CredentialSource: "no credentials"},
+		},
+	})
+
+	text := plan.Text()
+	for _, want := range []string{
+		"- registry.example.com (credentials from DRAIDE_USERNAME environment variable)",
+		"- docker.io (no credentials)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("plan text misses %q:\n%s", want, text)
+		}
+	}
+}
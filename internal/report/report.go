@@ -0,0 +1,107 @@
+package report
+
+import (
+	"io/ioutil"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+)
+
+// Report summarizes the outcome of a draide run in machine readable form
+type Report struct {
+	Command string `json:"command"`
+	// ExitCode and Class describe how the run ended, see package failure for the table of exit codes
+	ExitCode int      `json:"exitCode"`
+	Class    string   `json:"class"`
+	Images   []*Image `json:"images"`
+}
+
+// Image describes an image handled during a run
+type Image struct {
+	Name       string            `json:"name,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	BuildArgs  map[string]string `json:"buildArgs,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Tags       []string          `json:"tags"`
+	ImageID    string            `json:"imageId,omitempty"`
+	Built      bool              `json:"built"`
+	Reused     bool              `json:"reused"`
+	ReusedFrom string            `json:"reusedFrom,omitempty"`
+	Digest     string            `json:"digest,omitempty"`
+	Pushed     bool              `json:"pushed"`
+	// PushedTags lists the tags pushed so far, which differs from Tags if pushing failed or was cancelled
+	PushedTags []string `json:"pushedTags,omitempty"`
+	// Timings are only set for images built or pushed during the run
+	Timings *Timings    `json:"timings,omitempty"`
+	Steps   []*Step     `json:"steps,omitempty"`
+	Cache   *CacheStats `json:"cache,omitempty"`
+	// Tests are the results of the checks of the test section run against the built image
+	Tests []*TestResult `json:"tests,omitempty"`
+}
+
+// Timings describes where the time of building and pushing an image was spent
+type Timings struct {
+	ContextSize    int64        `json:"contextSizeBytes,omitempty"`
+	PackingSeconds float64      `json:"packingSeconds,omitempty"`
+	UploadSeconds  float64      `json:"uploadSeconds,omitempty"`
+	BuildSeconds   float64      `json:"buildSeconds,omitempty"`
+	Pushes         []*PushedTag `json:"pushes,omitempty"`
+}
+
+// Step describes a step of a build
+type Step struct {
+	Number      int     `json:"step"`
+	Instruction string  `json:"instruction"`
+	Seconds     float64 `json:"seconds"`
+	Cached      bool    `json:"cached"`
+	LayerID     string  `json:"layerId,omitempty"`
+}
+
+// TestResult describes the outcome of a check run against an image
+type TestResult struct {
+	Name string `json:"name"`
+	// Kind is the kind of the check: command, file, exposedPorts, nonRoot or labels
+	Kind    string  `json:"kind"`
+	Passed  bool    `json:"passed"`
+	Message string  `json:"message,omitempty"`
+	Output  string  `json:"output,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// PushedTag describes the push of a single tag
+type PushedTag struct {
+	Tag     string  `json:"tag"`
+	Seconds float64 `json:"seconds"`
+}
+
+// New creates an empty report for a command
+func New(command string) *Report {
+	return &Report{
+		Command: command,
+		Class:   string(failure.Success),
+		Images:  []*Image{},
+	}
+}
+
+// AddImage appends an image record to the report
+func (r *Report) AddImage(image *Image) *Image {
+	r.Images = append(r.Images, image)
+	return image
+}
+
+// SetExitCode records the exit code of the run together with its failure class
+func (r *Report) SetExitCode(code int) {
+	r.ExitCode = code
+	r.Class = string(failure.ClassOfCode(code))
+}
+
+// Write stores the report as JSON file. Secret values are redacted.
+func (r *Report) Write(path string) error {
+	content, err := ui.MarshalRedacted(r)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
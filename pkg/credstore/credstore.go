@@ -0,0 +1,309 @@
+package credstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/registry"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// dockerHubServerAddress is the key the docker CLI uses for Docker Hub credentials
+const dockerHubServerAddress = "https://index.docker.io/v1/"
+
+// authEntry is an entry of the auths section. Its fields are kept raw, so that fields draide does not know,
+// such as identitytoken or email, survive rewriting the file.
+type authEntry map[string]json.RawMessage
+
+// auth returns the base64 encoded username and password of the entry
+func (e authEntry) auth() string {
+	var auth string
+	if value, ok := e["auth"]; ok {
+		json.Unmarshal(value, &auth)
+	}
+	return auth
+}
+
+// dockerConfig holds the parts of the docker CLI configuration which are relevant for credentials
+type dockerConfig struct {
+	Auths       map[string]authEntry
+	CredsStore  string
+	CredHelpers map[string]string
+
+	raw map[string]json.RawMessage
+}
+
+// Path returns the location of the docker CLI configuration file
+func Path() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// Get returns the stored credentials for a registry. Empty credentials are returned if none are stored.
+func Get(registryName string) (registry.Credentials, error) {
+	cfg, err := load()
+	if err != nil {
+		return registry.Credentials{}, err
+	}
+
+	address := serverAddress(registryName)
+	if helper := cfg.helper(registryName); helper != "" {
+		return helperGet(helper, address)
+	}
+
+	for _, key := range cfg.keys(registryName) {
+		entry := cfg.Auths[key]
+		if entry.auth() == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.auth())
+		if err != nil {
+			return registry.Credentials{}, fmt.Errorf("invalid auth entry for %s in %s", key, cfg.path())
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return registry.Credentials{}, fmt.Errorf("invalid auth entry for %s in %s", key, cfg.path())
+		}
+		return registry.Credentials{Username: parts[0], Password: parts[1]}, nil
+	}
+
+	return registry.Credentials{}, nil
+}
+
+// Store saves credentials for a registry, either in the configured credential helper or in the docker CLI configuration file
+func Store(registryName string, creds registry.Credentials) error {
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	address := serverAddress(registryName)
+	entry := cfg.Auths[address]
+	// entries under other spellings of the address, such as https://host or host/v1/, would shadow the stored credentials
+	for _, key := range cfg.keys(registryName) {
+		if key != address {
+			if entry == nil {
+				entry = cfg.Auths[key]
+			}
+			delete(cfg.Auths, key)
+		}
+	}
+	if entry == nil {
+		entry = authEntry{}
+	}
+	if helper := cfg.helper(registryName); helper != "" {
+		if err := helperRun(helper, "store", map[string]string{
+			"ServerURL": address,
+			"Username":  creds.Username,
+			"Secret":    creds.Password,
+		}); err != nil {
+			return err
+		}
+		// the docker CLI keeps an entry without auth for registries whose credentials are held by a helper
+		delete(entry, "auth")
+	} else {
+		auth, err := json.Marshal(base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password)))
+		if err != nil {
+			return err
+		}
+		entry["auth"] = auth
+	}
+
+	cfg.Auths[address] = entry
+	return cfg.save()
+}
+
+// Erase removes stored credentials of a registry
+func Erase(registryName string) error {
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	helper := cfg.helper(registryName)
+	if helper != "" {
+		if err := helperRun(helper, "erase", serverAddress(registryName)); err != nil {
+			return err
+		}
+	}
+
+	found := helper != ""
+	for key := range cfg.Auths {
+		if registry.NormalizeRegistry(key) == registry.NormalizeRegistry(registryName) {
+			delete(cfg.Auths, key)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("not logged in to %s", registryName)
+	}
+
+	return cfg.save()
+}
+
+// serverAddress returns the key under which the docker CLI stores credentials of a registry
+func serverAddress(registryName string) string {
+	registryName = registry.NormalizeRegistry(registryName)
+	if registryName == registry.DockerHub {
+		return dockerHubServerAddress
+	}
+	return registryName
+}
+
+func load() (*dockerConfig, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &dockerConfig{
+		Auths:       map[string]authEntry{},
+		CredHelpers: map[string]string{},
+		raw:         map[string]json.RawMessage{},
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &cfg.raw); err != nil {
+		return nil, fmt.Errorf("failed parsing %s: %v", path, err)
+	}
+	for key, target := range map[string]interface{}{
+		"auths":       &cfg.Auths,
+		"credsStore":  &cfg.CredsStore,
+		"credHelpers": &cfg.CredHelpers,
+	} {
+		if value, ok := cfg.raw[key]; ok {
+			if err := json.Unmarshal(value, target); err != nil {
+				return nil, fmt.Errorf("failed parsing %s in %s: %v", key, path, err)
+			}
+		}
+	}
+	if cfg.Auths == nil {
+		cfg.Auths = map[string]authEntry{}
+	}
+
+	return cfg, nil
+}
+
+// save writes the configuration back while keeping all unrelated settings and fields of auths entries untouched
+func (cfg *dockerConfig) save() error {
+	path := cfg.path()
+
+	auths, err := json.Marshal(cfg.Auths)
+	if err != nil {
+		return err
+	}
+	cfg.raw["auths"] = auths
+
+	content, err := json.MarshalIndent(cfg.raw, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// keys returns the keys of the auths entries of a registry: the key the docker CLI uses first, then all other
+// spellings of the registry's address in sorted order
+func (cfg *dockerConfig) keys(registryName string) []string {
+	address := serverAddress(registryName)
+	keys := []string{}
+	if _, ok := cfg.Auths[address]; ok {
+		keys = append(keys, address)
+	}
+
+	aliases := []string{}
+	for key := range cfg.Auths {
+		if key != address && registry.NormalizeRegistry(key) == registry.NormalizeRegistry(registryName) {
+			aliases = append(aliases, key)
+		}
+	}
+	sort.Strings(aliases)
+
+	return append(keys, aliases...)
+}
+
+func (cfg *dockerConfig) path() string {
+	path, _ := Path()
+	return path
+}
+
+// helper returns the name of the credential helper responsible for a registry
+func (cfg *dockerConfig) helper(registryName string) string {
+	for key, helper := range cfg.CredHelpers {
+		if registry.NormalizeRegistry(key) == registry.NormalizeRegistry(registryName) {
+			return helper
+		}
+	}
+	return cfg.CredsStore
+}
+
+func helperGet(helper string, address string) (registry.Credentials, error) {
+	var out bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(address)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		// credential helpers signal missing credentials through a non-zero exit code
+		if strings.Contains(out.String(), "credentials not found") {
+			return registry.Credentials{}, nil
+		}
+		return registry.Credentials{}, fmt.Errorf("credential helper %s failed: %v", helper, err)
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out.Bytes(), &creds); err != nil {
+		return registry.Credentials{}, fmt.Errorf("invalid response from credential helper %s: %v", helper, err)
+	}
+
+	return registry.Credentials{Username: creds.Username, Password: creds.Secret}, nil
+}
+
+func helperRun(helper string, action string, input interface{}) error {
+	var stdin []byte
+	if s, ok := input.(string); ok {
+		stdin = []byte(s)
+	} else {
+		var err error
+		stdin, err = json.Marshal(input)
+		if err != nil {
+			return err
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, action)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("credential helper %s failed to %s credentials: %s", helper, action, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
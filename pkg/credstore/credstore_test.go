@@ -0,0 +1,241 @@
+package credstore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/registry"
+)
+
+// useConfigDir points DOCKER_CONFIG at a temporary directory holding the given config.json, if not empty
+func useConfigDir(t *testing.T, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "draide-credstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if content != "" {
+		if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous, set := os.LookupEnv("DOCKER_CONFIG")
+	os.Setenv("DOCKER_CONFIG", dir)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv("DOCKER_CONFIG", previous)
+		} else {
+			os.Unsetenv("DOCKER_CONFIG")
+		}
+	})
+	return dir
+}
+
+func readConfig(t *testing.T, dir string) map[string]interface{} {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestStoreKeepsUnrelatedEntries(t *testing.T) {
+	dir := useConfigDir(t, `{
+		"auths": {
+			"other.example.com": {"auth": "eDp5", "identitytoken": "tok", "email": "a@example.com"},
+			"reg.example.com": {"auth": "b2xkOm9sZA==", "email": "b@example.com"}
+		},
+		"psFormat": "table {{.ID}}"
+	}`)
+
+	if err := Store("reg.example.com", registry.Credentials{Username: "alice", Password: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := readConfig(t, dir)
+	if cfg["psFormat"] != "table {{.ID}}" {
+		t.Errorf("unrelated setting lost: %v", cfg)
+	}
+	auths := cfg["auths"].(map[string]interface{})
+	other := auths["other.example.com"].(map[string]interface{})
+	if other["identitytoken"] != "tok" || other["email"] != "a@example.com" || other["auth"] != "eDp5" {
+		t.Errorf("entry of other registry changed: %v", other)
+	}
+	target := auths["reg.example.com"].(map[string]interface{})
+	if target["email"] != "b@example.com" {
+		t.Errorf("unknown field of target entry lost: %v", target)
+	}
+
+	creds, err := Get("https://reg.example.com/v2/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Username != "alice" || creds.Password != "s3cret" {
+		t.Errorf("Get() = %+v", creds)
+	}
+}
+
+func TestStoreReplacesAliases(t *testing.T) {
+	dir := useConfigDir(t, `{"auths": {
+		"https://reg.example.com": {"auth": "b2xkOm9sZA==", "email": "b@example.com"},
+		"reg.example.com/v1/": {"auth": "b2xkZXI6b2xkZXI="},
+		"other.example.com": {"auth": "eDp5"}
+	}}`)
+
+	if err := Store("reg.example.com", registry.Credentials{Username: "alice", Password: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	auths := readConfig(t, dir)["auths"].(map[string]interface{})
+	if len(auths) != 2 || auths["reg.example.com"] == nil || auths["other.example.com"] == nil {
+		t.Errorf("aliases not replaced: %v", auths)
+	}
+	if target := auths["reg.example.com"].(map[string]interface{}); target["email"] != "b@example.com" {
+		t.Errorf("unknown field of aliased entry lost: %v", target)
+	}
+	creds, err := Get("https://reg.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Username != "alice" || creds.Password != "s3cret" {
+		t.Errorf("Get() = %+v", creds)
+	}
+}
+
+func TestGetPrefersExactKey(t *testing.T) {
+	useConfigDir(t, `{"auths": {
+		"https://reg.example.com/v2/": {"auth": "djI6djI="},
+		"https://reg.example.com": {"auth": "aHR0cHM6aHR0cHM="},
+		"reg.example.com": {"auth": "ZXhhY3Q6ZXhhY3Q="}
+	}}`)
+
+	for i := 0; i < 10; i++ {
+		creds, err := Get("reg.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.Username != "exact" {
+			t.Fatalf("Get() = %+v, want the credentials of the exact key", creds)
+		}
+	}
+
+	// without an exact key, the first alias in sorted order is used
+	useConfigDir(t, `{"auths": {
+		"https://reg.example.com/v2/": {"auth": "djI6djI="},
+		"https://reg.example.com": {"auth": "aHR0cHM6aHR0cHM="}
+	}}`)
+	for i := 0; i < 10; i++ {
+		creds, err := Get("reg.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.Username != "https" {
+			t.Fatalf("Get() = %+v, want the credentials of https://reg.example.com", creds)
+		}
+	}
+}
+
+func TestStoreCreatesConfig(t *testing.T) {
+	dir := useConfigDir(t, "")
+
+	if err := Store("", registry.Credentials{Username: "alice", Password: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	auths := readConfig(t, dir)["auths"].(map[string]interface{})
+	if _, ok := auths[dockerHubServerAddress]; !ok {
+		t.Errorf("Docker Hub credentials not stored under %s: %v", dockerHubServerAddress, auths)
+	}
+}
+
+func TestErase(t *testing.T) {
+	dir := useConfigDir(t, `{"auths": {
+		"other.example.com": {"auth": "eDp5", "identitytoken": "tok"},
+		"reg.example.com": {"auth": "YWxpY2U6czNjcmV0"}
+	}}`)
+
+	if err := Erase("reg.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	auths := readConfig(t, dir)["auths"].(map[string]interface{})
+	if _, ok := auths["reg.example.com"]; ok {
+		t.Errorf("credentials not erased: %v", auths)
+	}
+	if other := auths["other.example.com"].(map[string]interface{}); other["identitytoken"] != "tok" {
+		t.Errorf("entry of other registry changed: %v", other)
+	}
+	if err := Erase("reg.example.com"); err == nil {
+		t.Error("expected an error when not logged in")
+	}
+}
+
+// fakeHelper is a credential helper storing a single credential next to itself
+const fakeHelper = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+store) cat > "$dir/stored.json" ;;
+get)
+	cat > "$dir/requested"
+	if [ -f "$dir/stored.json" ]; then cat "$dir/stored.json"; else echo "credentials not found in native keychain"; exit 1; fi ;;
+erase) cat > "$dir/erased"; rm -f "$dir/stored.json" ;;
+esac
+`
+
+func TestCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helper is a shell script")
+	}
+	dir := useConfigDir(t, `{"credHelpers": {"reg.example.com": "fake"}, "auths": {"other.example.com": {"auth": "eDp5"}}}`)
+	if err := ioutil.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(fakeHelper), 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+
+	creds, err := Get("reg.example.com")
+	if err != nil || creds.Username != "" {
+		t.Fatalf("Get() before storing = %+v, %v", creds, err)
+	}
+
+	if err := Store("reg.example.com", registry.Credentials{Username: "alice", Password: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	auths := readConfig(t, dir)["auths"].(map[string]interface{})
+	if entry := auths["reg.example.com"].(map[string]interface{}); entry["auth"] != nil {
+		t.Errorf("password stored in config file although a helper is configured: %v", entry)
+	}
+
+	creds, err = Get("reg.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Username != "alice" || creds.Password != "s3cret" {
+		t.Errorf("Get() = %+v", creds)
+	}
+	if requested, _ := ioutil.ReadFile(filepath.Join(dir, "requested")); string(requested) != "reg.example.com" {
+		t.Errorf("helper asked for %q", requested)
+	}
+
+	if err := Erase("reg.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if erased, _ := ioutil.ReadFile(filepath.Join(dir, "erased")); string(erased) != "reg.example.com" {
+		t.Errorf("helper erased %q", erased)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stored.json")); !os.IsNotExist(err) {
+		t.Error("credentials still stored in helper")
+	}
+}
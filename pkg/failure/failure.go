@@ -0,0 +1,102 @@
+// Package failure classifies errors so that draide exits with a code telling pipelines what went wrong.
+//
+// Exit codes:
+//
+//	0  success
+//	1  general: any failure not covered by another class
+//	2  config-invalid: invalid configuration, flags or templates
+//	3  daemon-unreachable: the Docker daemon cannot be reached
+//	4  build-failed: a build step failed
+//	5  auth-rejected: a registry rejected the credentials
+//	6  push-failed: pushing failed even after retries
+//	7  nothing-to-do: there was nothing to build or push, only used with --detailed-exit-code
+//	8  cancelled: the run was cancelled by SIGINT or SIGTERM
+//	9  test-failed: a check of the test section failed against the built image
+//
+// The run command exits with the exit code of the container instead, reported with class container-exited.
+package failure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Class identifies the kind of a failure
+type Class string
+
+// Failure classes
+const (
+	Success           Class = "success"
+	General           Class = "general"
+	ConfigInvalid     Class = "config-invalid"
+	DaemonUnreachable Class = "daemon-unreachable"
+	BuildFailed       Class = "build-failed"
+	AuthRejected      Class = "auth-rejected"
+	PushFailed        Class = "push-failed"
+	NothingToDo       Class = "nothing-to-do"
+	Cancelled         Class = "cancelled"
+	TestFailed        Class = "test-failed"
+	// ContainerExited has no exit code of its own, the run command exits with the code of the container
+	ContainerExited Class = "container-exited"
+)
+
+var classes = []Class{Success, General, ConfigInvalid, DaemonUnreachable, BuildFailed, AuthRejected, PushFailed, NothingToDo, Cancelled, TestFailed}
+
+// Code returns the exit code of a class
+func (c Class) Code() int {
+	for code, class := range classes {
+		if class == c {
+			return code
+		}
+	}
+	return General.Code()
+}
+
+// ClassOfCode returns the class of an exit code
+func ClassOfCode(code int) Class {
+	if code < 0 || code >= len(classes) {
+		return General
+	}
+	return classes[code]
+}
+
+// Error is an error of a failure class
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of a class
+func New(class Class, format string, args ...interface{}) error {
+	return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap assigns a class to an error. Errors which already have a class keep it.
+func Wrap(class Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	return &Error{Class: class, Err: err}
+}
+
+// ClassOf returns the class of an error, General if it has none
+func ClassOf(err error) Class {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	return General
+}
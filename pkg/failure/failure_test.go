@@ -0,0 +1,55 @@
+package failure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestCodes pins the documented exit codes, which pipelines depend on
+func TestCodes(t *testing.T) {
+	tests := []struct {
+		class Class
+		code  int
+	}{
+		{Success, 0},
+		{General, 1},
+		{ConfigInvalid, 2},
+		{DaemonUnreachable, 3},
+		{BuildFailed, 4},
+		{AuthRejected, 5},
+		{PushFailed, 6},
+		{NothingToDo, 7},
+		{Cancelled, 8},
+		{TestFailed, 9},
+	}
+	if len(tests) != len(classes) {
+		t.Fatalf("%d classes, but %d are pinned", len(classes), len(tests))
+	}
+	for _, test := range tests {
+		if code := test.class.Code(); code != test.code {
+			t.Errorf("%s.Code() = %d, want %d", test.class, code, test.code)
+		}
+		if class := ClassOfCode(test.code); class != test.class {
+			t.Errorf("ClassOfCode(%d) = %s, want %s", test.code, class, test.class)
+		}
+	}
+
+	if code := Class("unknown").Code(); code != 1 {
+		t.Errorf("unknown class code = %d, want 1", code)
+	}
+	if class := ClassOfCode(42); class != General {
+		t.Errorf("ClassOfCode(42) = %s, want general", class)
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	err := fmt.Errorf("pushing: %w", Wrap(PushFailed, Wrap(AuthRejected, errors.New("denied"))))
+
+	if class := ClassOf(err); class != AuthRejected {
+		t.Errorf("ClassOf() = %s, want the innermost class auth-rejected", class)
+	}
+	if class := ClassOf(errors.New("plain")); class != General {
+		t.Errorf("ClassOf() = %s, want general", class)
+	}
+}
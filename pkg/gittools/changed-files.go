@@ -0,0 +1,139 @@
+package gittools
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangeSet lists files which changed between a base revision and the working tree
+type ChangeSet struct {
+	// Root is the absolute path of the repository's working tree
+	Root string
+	// Base is the commit hash changes are compared against
+	Base string
+	// Files contains changed paths relative to Root, using forward slashes
+	Files []string
+}
+
+// ChangedFiles returns all files changed between the given revision and the working tree, including uncommitted changes.
+// Untracked files are ignored, like IsDirty does. If since is empty, the merge-base of HEAD with the default branch is used.
+func ChangedFiles(path string, since string) (*ChangeSet, error) {
+	repo, err := openRepository(path)
+	if err != nil {
+		return nil, err
+	}
+
+	headRef, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := repo.CommitObject(headRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	var baseCommit *object.Commit
+	if since == "" {
+		baseCommit, err = mergeBaseWithDefaultBranch(repo, headCommit)
+	} else {
+		var hash *plumbing.Hash
+		hash, err = repo.ResolveRevision(plumbing.Revision(since))
+		if err == nil {
+			baseCommit, err = repo.CommitObject(*hash)
+		}
+	}
+	if err != nil {
+		return nil, failure.New(failure.ConfigInvalid, "failed resolving base revision: %v", err)
+	}
+
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files[change.From.Name] = true
+		}
+		if change.To.Name != "" {
+			files[change.To.Name] = true
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+	for file, s := range status {
+		if s.Worktree == git.Untracked {
+			continue
+		}
+		if s.Staging != git.Unmodified || s.Worktree != git.Unmodified {
+			files[filepath.ToSlash(file)] = true
+		}
+	}
+
+	changeSet := ChangeSet{
+		Root:  worktree.Filesystem.Root(),
+		Base:  baseCommit.Hash.String(),
+		Files: make([]string, 0, len(files)),
+	}
+	for file := range files {
+		changeSet.Files = append(changeSet.Files, file)
+	}
+
+	return &changeSet, nil
+}
+
+// mergeBaseWithDefaultBranch returns the best common ancestor of the given commit and the default branch
+func mergeBaseWithDefaultBranch(repo *git.Repository, commit *object.Commit) (*object.Commit, error) {
+	candidates := []plumbing.ReferenceName{
+		plumbing.NewRemoteReferenceName("origin", "HEAD"),
+		plumbing.NewRemoteReferenceName("origin", "main"),
+		plumbing.NewRemoteReferenceName("origin", "master"),
+		plumbing.NewBranchReferenceName("main"),
+		plumbing.NewBranchReferenceName("master"),
+	}
+
+	for _, name := range candidates {
+		ref, err := repo.Reference(name, true)
+		if err != nil {
+			continue
+		}
+		defaultBranchCommit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+
+		bases, err := commit.MergeBase(defaultBranchCommit)
+		if err != nil {
+			return nil, err
+		}
+		if len(bases) == 0 {
+			return nil, fmt.Errorf("no common ancestor with %s", name.Short())
+		}
+		return bases[0], nil
+	}
+
+	return nil, errors.New("unable to determine default branch")
+}
@@ -0,0 +1,135 @@
+package gittools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes files to the worktree and commits them
+func commitFiles(t *testing.T, dir string, repo *git.Repository, files map[string]string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "draide", Email: "draide@example.com", When: time.Now()}
+	hash, err := worktree.Commit("change", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func changedFiles(t *testing.T, dir string, since string) []string {
+	t.Helper()
+	changeSet, err := ChangedFiles(dir, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(changeSet.Files)
+	return changeSet.Files
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir, repo := initRepo(t)
+	head, _ := repo.Head()
+	base := head.Hash().String()
+
+	// on the default branch without changes, nothing changed since its merge-base
+	if got := changedFiles(t, dir, ""); len(got) != 0 {
+		t.Errorf("ChangedFiles() = %v, want none", got)
+	}
+
+	commitFiles(t, dir, repo, map[string]string{"svc/a/main.go": "package main\n"})
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "staged.txt"), []byte("staged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := repo.Worktree()
+	if _, err := worktree.Add("staged.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// untracked files, such as reports written by draide itself, are no change
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Dockerfile", "staged.txt", "svc/a/main.go"}
+	if got := changedFiles(t, dir, base); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedFiles(%s) = %v, want %v", base, got, want)
+	}
+	// HEAD is the default branch, so only uncommitted changes are found by default
+	if got := changedFiles(t, dir, ""); !reflect.DeepEqual(got, []string{"Dockerfile", "staged.txt"}) {
+		t.Errorf("ChangedFiles() = %v", got)
+	}
+
+	changeSet, err := ChangedFiles(filepath.Join(dir, "svc"), "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changeSet.Root != dir || changeSet.Base != base {
+		t.Errorf("Root = %s, Base = %s, want %s, %s", changeSet.Root, changeSet.Base, dir, base)
+	}
+}
+
+func TestChangedFilesSinceMergeBase(t *testing.T) {
+	dir, repo := initRepo(t)
+	head, _ := repo.Head()
+	base := head.Hash()
+
+	worktree, _ := repo.Worktree()
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, dir, repo, map[string]string{"feature.txt": "feature\n"})
+	// commits of the default branch after the merge-base are not part of the changes
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}); err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, dir, repo, map[string]string{"master.txt": "master\n"})
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature")}); err != nil {
+		t.Fatal(err)
+	}
+
+	changeSet, err := ChangedFiles(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changeSet.Base != base.String() || !reflect.DeepEqual(changeSet.Files, []string{"feature.txt"}) {
+		t.Errorf("ChangedFiles() = %s %v, want %s [feature.txt]", changeSet.Base, changeSet.Files, base)
+	}
+}
+
+func TestChangedFilesUnknownRevision(t *testing.T) {
+	dir, _ := initRepo(t)
+
+	_, err := ChangedFiles(dir, "does-not-exist")
+	if err == nil || failure.ClassOf(err) != failure.ConfigInvalid {
+		t.Errorf("ChangedFiles() error = %v, want a config-invalid failure", err)
+	}
+}
@@ -0,0 +1,23 @@
+package gittools
+
+import "fmt"
+
+// GetRemoteURL returns the first URL of the given remote of the repository containing path
+func GetRemoteURL(path string, name string) (string, error) {
+	repo, err := openRepository(path)
+	if err != nil {
+		return "", err
+	}
+
+	remote, err := repo.Remote(name)
+	if err != nil {
+		return "", err
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %s has no URL", name)
+	}
+
+	return urls[0], nil
+}
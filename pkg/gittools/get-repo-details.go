@@ -1,29 +1,28 @@
 package gittools
 
 import (
-	"github.com/marcelriegr/draide/pkg/ui"
-	"github.com/mitchellh/go-homedir"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 // RepoDetails contains repository info
 type RepoDetails struct {
 	Branch     string
 	CommitHash string
+	// Tag is the tag pointing at HEAD, empty if there is none. If multiple tags point at HEAD, the highest semantic version
+	// is used, or the most recent annotated tag if none is a semantic version.
+	Tag string
 }
 
 // GetRepoDetails return repository info
 func GetRepoDetails(path string) (*RepoDetails, error) {
-	path, err := homedir.Expand(path)
-	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed parsing git repository information")
-	}
-
-	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
-		DetectDotGit: true,
-	})
+	repo, err := openRepository(path)
 	if err != nil {
 		return nil, err
 	}
@@ -37,5 +36,143 @@ func GetRepoDetails(path string) (*RepoDetails, error) {
 	details.CommitHash = headRef.Hash().String()
 	details.Branch = headRef.Name().Short()
 
+	tags, err := tagsPointingAt(repo, headRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		details.Tag = tags[0].name
+	}
+
 	return &details, nil
 }
+
+var semverTagPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+type commitTag struct {
+	name string
+	// date is the tagger date of annotated tags and zero for lightweight tags
+	date time.Time
+}
+
+// tagsPointingAt returns all lightweight and annotated tags pointing at a commit, the preferred tag first.
+// Semantic versions are preferred over other tags and sorted by precedence, other tags by tagger date and name.
+func tagsPointingAt(repo *git.Repository, commit plumbing.Hash) ([]commitTag, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []commitTag{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		target := ref.Hash()
+		tag := commitTag{name: ref.Name().Short()}
+		if object, err := repo.TagObject(target); err == nil {
+			target = object.Target
+			tag.date = object.Tagger.When
+		}
+		if target == commit {
+			tags = append(tags, tag)
+		}
+		return nil
+	})
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tagPrecedes(tags[i], tags[j])
+	})
+
+	return tags, err
+}
+
+// tagPrecedes reports whether tag a is preferred over tag b
+func tagPrecedes(a, b commitTag) bool {
+	versionA := semverTagPattern.FindStringSubmatch(a.name)
+	versionB := semverTagPattern.FindStringSubmatch(b.name)
+	switch {
+	case versionA != nil && versionB != nil:
+		if c := compareSemver(versionA, versionB); c != 0 {
+			return c > 0
+		}
+	case versionA != nil || versionB != nil:
+		return versionA != nil
+	case !a.date.Equal(b.date):
+		return a.date.After(b.date)
+	}
+	return a.name > b.name
+}
+
+// compareSemver compares two matches of semverTagPattern by semantic version precedence
+func compareSemver(a, b []string) int {
+	for i := 1; i <= 3; i++ {
+		if c := compareNumeric(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	// a version without pre-release has a higher precedence than one with
+	switch {
+	case a[4] == b[4]:
+		return 0
+	case a[4] == "":
+		return 1
+	case b[4] == "":
+		return -1
+	}
+
+	fieldsA, fieldsB := strings.Split(a[4], "."), strings.Split(b[4], ".")
+	for i := 0; i < len(fieldsA) && i < len(fieldsB); i++ {
+		_, errA := strconv.ParseUint(fieldsA[i], 10, 64)
+		_, errB := strconv.ParseUint(fieldsB[i], 10, 64)
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareNumeric(fieldsA[i], fieldsB[i])
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(fieldsA[i], fieldsB[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return len(fieldsA) - len(fieldsB)
+}
+
+// compareNumeric compares two strings of digits by their numeric value without limiting their size
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// IsDirty reports whether the repository containing path has uncommitted changes to tracked files.
+// Untracked files, such as reports written by draide itself, are ignored.
+func IsDirty(path string) (bool, error) {
+	repo, err := openRepository(path)
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+
+	for _, file := range status {
+		if file.Worktree == git.Untracked {
+			continue
+		}
+		if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,101 @@
+package gittools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestTagPrecedes(t *testing.T) {
+	now := time.Now()
+	tags := []commitTag{
+		{name: "v1.9.0"},
+		{name: "latest", date: now},
+		{name: "v1.10.0-rc.1"},
+		{name: "v1.10.0"},
+		{name: "v1.10.0-rc.2"},
+		{name: "v1.10.0-beta"},
+		{name: "release", date: now.Add(-time.Hour)},
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tagPrecedes(tags[i], tags[j])
+	})
+
+	want := []string{"v1.10.0", "v1.10.0-rc.2", "v1.10.0-rc.1", "v1.10.0-beta", "v1.9.0", "latest", "release"}
+	for i, tag := range tags {
+		if tag.name != want[i] {
+			t.Fatalf("tags sorted as %v, want %v", tags, want)
+		}
+	}
+}
+
+func TestGetRepoDetailsTag(t *testing.T) {
+	dir, repo := initRepo(t)
+	head, _ := repo.Head()
+	for _, name := range []string{"v1.9.0", "v1.10.0", "v1.2.0"} {
+		if _, err := repo.CreateTag(name, head.Hash(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	details, err := GetRepoDetails(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Tag != "v1.10.0" {
+		t.Errorf("Tag = %s, want v1.10.0", details.Tag)
+	}
+}
+
+func TestIsDirty(t *testing.T) {
+	dir, _ := initRepo(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := IsDirty(dir); err != nil || dirty {
+		t.Errorf("IsDirty() with untracked file = %v, %v, want false", dirty, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := IsDirty(dir); err != nil || !dirty {
+		t.Errorf("IsDirty() with modified file = %v, %v, want true", dirty, err)
+	}
+}
+
+// initRepo creates a repository with a single commit of a Dockerfile
+func initRepo(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "draide-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "draide", Email: "draide@example.com", When: time.Now()}
+	if _, err := worktree.Commit("init", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+	return dir, repo
+}
@@ -0,0 +1,16 @@
+package gittools
+
+// GetRootDir returns the root of the working tree of the repository containing path
+func GetRootDir(path string) (string, error) {
+	repo, err := openRepository(path)
+	if err != nil {
+		return "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	return worktree.Filesystem.Root(), nil
+}
@@ -0,0 +1,26 @@
+package gittools
+
+import (
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// openRepository opens the repository containing path. A path outside of any repository is a config-invalid failure,
+// as it is the configuration which asks for git information.
+func openRepository(path string) (*git.Repository, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err == git.ErrRepositoryNotExists {
+		return nil, failure.New(failure.ConfigInvalid, "%s is not inside a git repository", path)
+	}
+	return repo, err
+}
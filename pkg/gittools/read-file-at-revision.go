@@ -0,0 +1,38 @@
+package gittools
+
+import (
+	"fmt"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ReadFileAtRevision returns the content of a file at the given revision of the repository containing repoPath.
+// The file path is relative to the root of the repository and uses forward slashes.
+func ReadFileAtRevision(repoPath string, revision string, file string) ([]byte, error) {
+	repo, err := openRepository(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, failure.New(failure.ConfigInvalid, "failed resolving revision %s: %v", revision, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := commit.File(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s at %s: %v", file, revision, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
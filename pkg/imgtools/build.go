@@ -2,14 +2,14 @@ package imgtools
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
 
+	"github.com/marcelriegr/draide/pkg/failure"
 	"github.com/marcelriegr/draide/pkg/ui"
 
-	"github.com/docker/docker/pkg/archive"
-	"github.com/docker/docker/pkg/jsonmessage"
-	"github.com/docker/docker/pkg/term"
-	"github.com/docker/engine-api/client"
 	"github.com/docker/engine-api/types"
 )
 
@@ -22,34 +22,111 @@ type BuildOptions struct {
 	NoCache    bool
 }
 
-// Build a docker image
-func Build(contextDir string, opts BuildOptions) {
-	cli, err := client.NewEnvClient()
+// BuildResult describes a built image
+type BuildResult struct {
+	ImageID string
+	// ContextSize is the size of the packed build context in bytes
+	ContextSize int64
+	// Packing is the time spent packing the build context. The context is packed while it is sent to the daemon.
+	Packing time.Duration
+	// Upload is the time from sending the build context until the daemon starts responding, without packing
+	Upload time.Duration
+	// Duration is the time the daemon spent building after receiving the context
+	Duration time.Duration
+	Steps    []*BuildStep
+}
+
+// BuildStep describes a step of a build, as reported by the build stream
+type BuildStep struct {
+	Number      int
+	Instruction string
+	Duration    time.Duration
+	// Cached reports whether the daemon reused the layer of a previous build
+	Cached bool
+	// LayerID is the ID of the image resulting from the step
+	LayerID string
+
+	started time.Time
+}
+
+// Build a docker image. Errors of a build step are reported at the failing Dockerfile instruction.
+// Cancelling the context aborts the build and removes the intermediate containers left behind.
+// Timings are returned even if the build fails.
+func Build(ctx context.Context, contextDir string, opts BuildOptions) (BuildResult, error) {
+	result := BuildResult{}
+	cli, err := newClient()
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed establishing connection to Docker engine")
+		return result, err
 	}
 
-	contextDirTar, err := archive.TarWithOptions(contextDir, &archive.TarOptions{})
+	contextDirTar, err := contextTar(contextDir, opts.Dockerfile)
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed reading context directory")
+		return result, failure.Wrap(failure.ConfigInvalid, fmt.Errorf("failed reading context directory: %w", err))
 	}
+	defer contextDirTar.Close()
+	packedContext := &countingReader{r: contextDirTar}
 
-	response, err := cli.ImageBuild(context.Background(), contextDirTar, types.ImageBuildOptions{
-		Dockerfile:     opts.Dockerfile,
-		Tags:           opts.Tags,
-		BuildArgs:      opts.BuildArgs,
-		Labels:         opts.Labels,
-		NoCache:        opts.NoCache,
-		SuppressOutput: !ui.IsVerbose(),
+	start := time.Now()
+	response, err := cli.ImageBuild(ctx, packedContext, types.ImageBuildOptions{
+		Dockerfile:  opts.Dockerfile,
+		Tags:        opts.Tags,
+		BuildArgs:   opts.BuildArgs,
+		Labels:      opts.Labels,
+		NoCache:     opts.NoCache,
+		Remove:      true,
+		ForceRemove: true,
 	})
+	result.ContextSize = packedContext.bytes
+	result.Packing = packedContext.busy
+	result.Upload = time.Since(start) - packedContext.busy
+	ui.Log("Sent build context of %d bytes, packing took %s", result.ContextSize, result.Packing)
+	if packedContext.err != nil {
+		return result, failure.Wrap(failure.ConfigInvalid, fmt.Errorf("failed reading context directory: %w", packedContext.err))
+	}
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed building image")
+		return result, classify(ctx, err, failure.BuildFailed)
 	}
 	defer response.Body.Close()
 
-	termFd, isTerm := term.GetFdInfo(os.Stdout)
-	jsonmessage.DisplayJSONMessagesStream(response.Body, os.Stdout, termFd, isTerm, nil)
+	// the full stream is always requested to time the steps and to find the intermediate containers removed on
+	// cancellation, but only rendered in verbose mode
+	start = time.Now()
+	summary, err := displayStream(response.Body, ui.IsVerbose())
+	result.Duration = time.Since(start)
+	result.Steps = summary.Steps
+	result.ImageID = summary.ImageID
+	if err != nil && ctx.Err() != nil {
+		removeContainers(cli, summary.Containers)
+	} else if err != nil {
+		if summary.Step > 0 {
+			dockerfile := filepath.Join(contextDir, opts.Dockerfile)
+			ui.ErrorAt(dockerfile, dockerfileLine(dockerfile, summary.Step, summary.Instruction), "Step %d failed: %s", summary.Step, err.Error())
+		}
+	}
+	if err != nil {
+		return result, classify(ctx, err, failure.BuildFailed)
+	}
+
+	return result, nil
+}
+
+// countingReader counts the bytes read from the packed build context and the time spent waiting for them, so that
+// packing and uploading can be timed separately while the context is streamed to the daemon
+type countingReader struct {
+	r     io.Reader
+	bytes int64
+	busy  time.Duration
+	// err is the error packing the context failed with, if any
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := c.r.Read(p)
+	c.busy += time.Since(start)
+	c.bytes += int64(n)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
 }
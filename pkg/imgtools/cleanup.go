@@ -0,0 +1,44 @@
+package imgtools
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/engine-api/client"
+	"github.com/docker/engine-api/types"
+)
+
+// cleanupTimeout limits how long to wait for the daemon to stop cancelled build steps
+const cleanupTimeout = 10 * time.Second
+
+// removeContainers waits briefly for intermediate containers of a cancelled build to stop and removes them.
+// The daemon usually removes them itself, so containers which no longer exist are skipped.
+func removeContainers(cli *client.Client, containers []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	for _, id := range containers {
+		for ctx.Err() == nil {
+			info, err := cli.ContainerInspect(ctx, id)
+			if err != nil || !info.State.Running {
+				break
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+
+		removeCtx, cancelRemove := context.WithTimeout(context.Background(), cleanupTimeout)
+		err := cli.ContainerRemove(removeCtx, id, types.ContainerRemoveOptions{Force: true})
+		cancelRemove()
+		switch {
+		case err == nil:
+			ui.Log("Removed intermediate container %s", id)
+		case client.IsErrContainerNotFound(err):
+			ui.Log("Intermediate container %s already removed", id)
+		default:
+			ui.Log(err.Error())
+			ui.Warning("Failed removing intermediate container %s", id)
+		}
+	}
+}
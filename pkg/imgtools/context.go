@@ -0,0 +1,93 @@
+package imgtools
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/fileutils"
+)
+
+// contextTar packs the build context the same way the docker CLI does, honouring .dockerignore
+func contextTar(contextDir string, dockerfile string) (io.ReadCloser, error) {
+	excludes, err := readDockerignore(contextDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Dockerfile and .dockerignore are always sent to the daemon, even if excluded
+	for _, file := range []string{dockerfile, ".dockerignore"} {
+		if excluded, _ := fileutils.Matches(file, excludes); excluded {
+			excludes = append(excludes, "!"+file)
+		}
+	}
+
+	return archive.TarWithOptions(contextDir, &archive.TarOptions{
+		ExcludePatterns: excludes,
+	})
+}
+
+func readDockerignore(contextDir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return dockerignore.ReadAll(f)
+}
+
+// ContextHash returns a stable hash of the files sent as build context, the Dockerfile and the resolved build arguments.
+// File contents, paths, types, the executable bit and link targets are taken into account, timestamps, ownership and
+// other permission bits are not, as they differ between checkouts.
+func ContextHash(contextDir string, dockerfile string, buildArgs map[string]string) (string, error) {
+	hash := sha256.New()
+
+	contextDirTar, err := contextTar(contextDir, dockerfile)
+	if err != nil {
+		return "", err
+	}
+	defer contextDirTar.Close()
+
+	tr := tar.NewReader(contextDirTar)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%c\x00%t\x00%s\x00", header.Name, header.Typeflag, header.Mode&0111 != 0, header.Linkname)
+		if _, err := io.Copy(hash, tr); err != nil {
+			return "", err
+		}
+	}
+
+	dockerfileContent, err := ioutil.ReadFile(filepath.Join(contextDir, dockerfile))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(hash, "dockerfile\x00%s\x00", dockerfileContent)
+
+	keys := make([]string, 0, len(buildArgs))
+	for k := range buildArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(hash, "arg\x00%s=%s\x00", k, buildArgs[k])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
@@ -0,0 +1,110 @@
+package imgtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeContext creates a build context with the given files and modes
+func writeContext(t *testing.T, files map[string]string, modes map[string]os.FileMode) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "draide-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if mode, ok := modes[name]; ok {
+			if err := os.Chmod(path, mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func contextHash(t *testing.T, dir string, buildArgs map[string]string) string {
+	t.Helper()
+	hash, err := ContextHash(dir, "Dockerfile", buildArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestContextHash(t *testing.T) {
+	base := map[string]string{
+		"Dockerfile":    "FROM scratch\nCOPY . /\n",
+		".dockerignore": "*.log\ntmp/\n",
+		"app/main.go":   "package main\n",
+		"run.sh":        "#!/bin/sh\n",
+	}
+	with := func(changes map[string]string) map[string]string {
+		files := map[string]string{}
+		for k, v := range base {
+			files[k] = v
+		}
+		for k, v := range changes {
+			files[k] = v
+		}
+		return files
+	}
+	want := contextHash(t, writeContext(t, base, nil), nil)
+	if len(want) != 16 {
+		t.Errorf("ContextHash() = %q, want 16 hex digits", want)
+	}
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		modes     map[string]os.FileMode
+		buildArgs map[string]string
+		same      bool
+	}{
+		{name: "same content", files: base, same: true},
+		{name: "ignored file", files: with(map[string]string{"debug.log": "x", "tmp/cache": "x"}), same: true},
+		{name: "permissions other than the executable bit", files: base, modes: map[string]os.FileMode{"app/main.go": 0600, "run.sh": 0664}, same: true},
+		{name: "executable bit", files: base, modes: map[string]os.FileMode{"run.sh": 0755}},
+		{name: "file content", files: with(map[string]string{"app/main.go": "package app\n"})},
+		{name: "new file", files: with(map[string]string{"app/util.go": "package main\n"})},
+		{name: "Dockerfile", files: with(map[string]string{"Dockerfile": "FROM alpine\n"})},
+		{name: "dockerignore", files: with(map[string]string{".dockerignore": "*.log\n"})},
+		{name: "build args", files: base, buildArgs: map[string]string{"VERSION": "1"}},
+	}
+	for _, tt := range tests {
+		dir := writeContext(t, tt.files, tt.modes)
+		// timestamps are not part of the hash
+		past := time.Now().Add(-time.Hour)
+		os.Chtimes(filepath.Join(dir, "app", "main.go"), past, past)
+
+		if got := contextHash(t, dir, tt.buildArgs); (got == want) != tt.same {
+			t.Errorf("%s: ContextHash() = %s, base hash %s, want equal: %v", tt.name, got, want, tt.same)
+		}
+	}
+}
+
+func TestContextHashBuildArgsOrder(t *testing.T) {
+	dir := writeContext(t, map[string]string{"Dockerfile": "FROM scratch\n"}, nil)
+	args := map[string]string{"A": "1", "B": "2", "C": "3", "D": "4"}
+
+	want := contextHash(t, dir, args)
+	for i := 0; i < 10; i++ {
+		if got := contextHash(t, dir, args); got != want {
+			t.Fatalf("ContextHash() = %s, want %s", got, want)
+		}
+	}
+	if got := contextHash(t, dir, map[string]string{"A": "12", "B": "2", "C": "3", "D": "4"}); got == want {
+		t.Error("ContextHash() did not change with a build argument")
+	}
+}
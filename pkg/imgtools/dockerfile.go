@@ -0,0 +1,69 @@
+package imgtools
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// dockerfileInstruction is an instruction of a Dockerfile with the line it starts at
+type dockerfileInstruction struct {
+	Line int
+	Text string
+}
+
+// dockerfileInstructions splits a Dockerfile into instructions, joining continuation lines like the builder does
+func dockerfileInstructions(content string) []dockerfileInstruction {
+	instructions := []dockerfileInstruction{}
+	current := (*dockerfileInstruction)(nil)
+
+	for i, line := range strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		continued := strings.HasSuffix(trimmed, "\\")
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "\\"))
+		if current == nil {
+			current = &dockerfileInstruction{Line: i + 1, Text: trimmed}
+		} else {
+			current.Text += " " + trimmed
+		}
+
+		if !continued {
+			instructions = append(instructions, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		instructions = append(instructions, *current)
+	}
+
+	return instructions
+}
+
+// dockerfileLine returns the line of the instruction executed in the given build step, or 0 if it cannot be determined.
+// The instruction as reported by the builder is preferred over the step number, as steps do not account for every instruction.
+func dockerfileLine(path string, step int, instruction string) int {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	instructions := dockerfileInstructions(string(content))
+
+	normalize := func(str string) string {
+		return strings.ToLower(strings.Join(strings.Fields(str), " "))
+	}
+	if step >= 1 && step <= len(instructions) && normalize(instructions[step-1].Text) == normalize(instruction) {
+		return instructions[step-1].Line
+	}
+	for _, i := range instructions {
+		if normalize(i.Text) == normalize(instruction) {
+			return i.Line
+		}
+	}
+	if step >= 1 && step <= len(instructions) {
+		return instructions[step-1].Line
+	}
+	return 0
+}
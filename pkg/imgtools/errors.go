@@ -0,0 +1,42 @@
+package imgtools
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/docker/engine-api/client"
+)
+
+// authErrorMessages are parts of daemon error messages signalling that a registry rejected the credentials.
+// They are specific to registry responses, as build errors such as "permission denied" of a RUN step are no auth failures.
+var authErrorMessages = []string{"unauthorized:", "authentication required", "denied: requested access", "incorrect username or password", "no basic auth credentials"}
+
+// newClient connects to the Docker engine configured via the environment
+func newClient() (*client.Client, error) {
+	cli, err := client.NewEnvClient()
+	return cli, failure.Wrap(failure.DaemonUnreachable, err)
+}
+
+// classify assigns a failure class to an error of the Docker daemon, falling back to the given class.
+// Errors caused by cancelling the context are classified as cancelled.
+func classify(ctx context.Context, err error, fallback failure.Class) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return failure.Wrap(failure.Cancelled, ctx.Err())
+	}
+	if errors.Is(err, client.ErrConnectionFailed) {
+		return failure.Wrap(failure.DaemonUnreachable, err)
+	}
+	message := strings.ToLower(err.Error())
+	for _, part := range authErrorMessages {
+		if strings.Contains(message, part) {
+			return failure.Wrap(failure.AuthRejected, err)
+		}
+	}
+	return failure.Wrap(fallback, err)
+}
@@ -0,0 +1,24 @@
+package imgtools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]failure.Class{
+		"denied: requested access to the resource is denied":                         failure.AuthRejected,
+		"unauthorized: authentication required":                                      failure.AuthRejected,
+		"Get https://registry.example.com/v2/: no basic auth credentials":            failure.AuthRejected,
+		"open /app/data: permission denied":                                          failure.BuildFailed,
+		"The command '/bin/sh -c chmod 600 /etc/shadow' returned a non-zero code: 1": failure.BuildFailed,
+	}
+	for message, want := range tests {
+		if class := failure.ClassOf(classify(context.Background(), errors.New(message), failure.BuildFailed)); class != want {
+			t.Errorf("classify(%q) = %s, want %s", message, class, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package imgtools
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/strslice"
+)
+
+// statPathNotFound is part of the error of the client for a missing path. The daemon answers with a plain 404 without
+// body, which the client only reports by its status text.
+var statPathNotFound = "request returned " + http.StatusText(http.StatusNotFound) + " for API route"
+
+// ImageConfig returns the configuration of a local image, such as its user, exposed ports and labels
+func ImageConfig(ctx context.Context, imageName string) (container.Config, error) {
+	cli, err := newClient()
+	if err != nil {
+		return container.Config{}, err
+	}
+
+	info, _, err := cli.ImageInspectWithRaw(ctx, imageName, false)
+	if err != nil {
+		return container.Config{}, classify(ctx, err, failure.General)
+	}
+	if info.Config == nil {
+		return container.Config{}, nil
+	}
+	return *info.Config, nil
+}
+
+// FileExists reports whether a path exists in the filesystem of a local image.
+// The path is looked up in a container which is created but never started, so the image needs no shell.
+// Only a missing path is reported as false, any other failure of the lookup is returned as error.
+func FileExists(ctx context.Context, imageName string, path string) (bool, error) {
+	cli, err := newClient()
+	if err != nil {
+		return false, err
+	}
+
+	// images without command, such as those built from scratch, cannot be created without one
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: imageName,
+		Cmd:   strslice.StrSlice{"draide-stat"},
+	}, &container.HostConfig{}, nil, "")
+	if err != nil {
+		return false, classify(ctx, err, failure.General)
+	}
+	ui.Log("Created container %s", created.ID)
+	defer removeContainer(cli, created.ID)
+
+	if _, err := cli.ContainerStatPath(ctx, created.ID, path); err != nil {
+		if strings.Contains(err.Error(), statPathNotFound) {
+			ui.Log("Stat of %s failed: %s", path, err.Error())
+			return false, nil
+		}
+		return false, classify(ctx, err, failure.General)
+	}
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package imgtools
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withDaemon points the Docker client at a stand-in of the daemon for the duration of a test
+func withDaemon(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	daemon := httptest.NewServer(handler)
+	t.Cleanup(daemon.Close)
+
+	previous, ok := os.LookupEnv("DOCKER_HOST")
+	os.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("DOCKER_HOST", previous)
+		} else {
+			os.Unsetenv("DOCKER_HOST")
+		}
+	})
+}
+
+func TestFileExists(t *testing.T) {
+	withDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/create"):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"abc"}`))
+		case r.Method == http.MethodHead && strings.HasSuffix(r.URL.Path, "/containers/abc/archive"):
+			switch r.URL.Query().Get("path") {
+			case "/etc/app":
+				stat := base64.StdEncoding.EncodeToString([]byte(`{"name":"app","size":0,"mode":2147484141}`))
+				w.Header().Set("X-Docker-Container-Path-Stat", stat)
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/containers/abc"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tests := []struct {
+		path    string
+		exists  bool
+		failing bool
+	}{
+		{path: "/etc/app", exists: true},
+		{path: "/missing", exists: false},
+		{path: "/broken", failing: true},
+	}
+	for _, test := range tests {
+		exists, err := FileExists(context.Background(), "app:1", test.path)
+		if (err != nil) != test.failing || exists != test.exists {
+			t.Errorf("FileExists(%s) = %v, %v, want %v and failing %v", test.path, exists, err, test.exists, test.failing)
+		}
+	}
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"os"
+	"fmt"
+	"time"
 
+	"github.com/marcelriegr/draide/pkg/credstore"
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/registry"
 	"github.com/marcelriegr/draide/pkg/ui"
 
-	"github.com/docker/docker/pkg/jsonmessage"
-	"github.com/docker/docker/pkg/term"
-	"github.com/docker/engine-api/client"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/engine-api/types"
 )
 
@@ -25,12 +27,35 @@ type PushOptions struct {
 	Auth AuthConfig
 }
 
-// Push a docker image
-func Push(imageName string, opts PushOptions) {
-	cli, err := client.NewEnvClient()
+// PushResult describes a pushed image
+type PushResult struct {
+	Digest   string
+	Duration time.Duration
+}
+
+// Push a docker image. Credentials stored via `draide login` are used if none are given.
+// Cancelling the context aborts the push.
+func Push(ctx context.Context, imageName string, opts PushOptions) (PushResult, error) {
+	if _, err := reference.ParseNamed(imageName); err != nil {
+		return PushResult{}, failure.Wrap(failure.ConfigInvalid, fmt.Errorf("invalid image name %s: %w", imageName, err))
+	}
+
+	cli, err := newClient()
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed establishing connection to Docker engine")
+		return PushResult{}, err
+	}
+
+	if opts.Auth.Username == "" {
+		registryName := registry.ParseReference(imageName).Registry
+		creds, err := credstore.Get(registryName)
+		if err != nil {
+			ui.Log(err.Error())
+			ui.Warning("Failed reading stored credentials for %s", registryName)
+		} else if creds.Username != "" {
+			ui.AddSecret(creds.Password)
+			ui.Log("Using stored credentials for %s", registryName)
+			opts.Auth = AuthConfig{Username: creds.Username, Password: creds.Password}
+		}
 	}
 
 	authConfigAsBytes, err := json.Marshal(types.AuthConfig{
@@ -38,22 +63,22 @@ func Push(imageName string, opts PushOptions) {
 		Password: opts.Auth.Password,
 	})
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed encoding credentials")
+		return PushResult{}, fmt.Errorf("failed encoding credentials: %w", err)
 	}
 
-	response, err := cli.ImagePush(context.Background(), imageName, types.ImagePushOptions{
+	start := time.Now()
+	response, err := cli.ImagePush(ctx, imageName, types.ImagePushOptions{
 		RegistryAuth: base64.URLEncoding.EncodeToString(authConfigAsBytes),
 	})
 	if err != nil {
-		ui.Log(err.Error())
-		ui.ErrorAndExit(1, "Failed pushing image")
+		return PushResult{}, classify(ctx, err, failure.PushFailed)
 	}
 	defer response.Close()
 
-	termFd, isTerm := term.GetFdInfo(os.Stdout)
-	err = jsonmessage.DisplayJSONMessagesStream(response, os.Stdout, termFd, isTerm, nil)
+	summary, err := displayStream(response, true)
 	if err != nil {
-		ui.ErrorAndExit(1, err.Error())
+		return PushResult{}, classify(ctx, err, failure.PushFailed)
 	}
+
+	return PushResult{Digest: summary.Digest, Duration: time.Since(start)}, nil
 }
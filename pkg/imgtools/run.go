@@ -0,0 +1,173 @@
+package imgtools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/engine-api/client"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/strslice"
+	"github.com/docker/go-connections/nat"
+	"github.com/mitchellh/go-homedir"
+)
+
+// RunOptions configures the container started by Run
+type RunOptions struct {
+	// Entrypoint overrides the entrypoint of the image if not empty
+	Entrypoint []string
+	// Cmd overrides the command of the image if not empty
+	Cmd []string
+	// Ports are published ports in docker run syntax, such as 8080:80
+	Ports []string
+	// Env lists environment variables as KEY=VALUE
+	Env []string
+	// Volumes are bind mounts or named volumes in docker run syntax, such as ./data:/data
+	Volumes []string
+	Network string
+	// Signals are forwarded to the container. Any signal after the first one kills the container.
+	Signals <-chan os.Signal
+	// Stdout and Stderr receive the output of the container, the output of draide if nil
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run starts a container of an image, streams its output until it exits and removes it.
+// It returns the exit code of the container.
+func Run(ctx context.Context, imageName string, opts RunOptions) (int, error) {
+	cli, err := newClient()
+	if err != nil {
+		return 0, err
+	}
+
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(opts.Ports)
+	if err != nil {
+		return 0, failure.Wrap(failure.ConfigInvalid, fmt.Errorf("invalid port: %w", err))
+	}
+	binds, err := volumeBinds(opts.Volumes)
+	if err != nil {
+		return 0, failure.Wrap(failure.ConfigInvalid, err)
+	}
+
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:        imageName,
+		Entrypoint:   strslice.StrSlice(opts.Entrypoint),
+		Cmd:          strslice.StrSlice(opts.Cmd),
+		Env:          opts.Env,
+		ExposedPorts: exposedPorts,
+		AttachStdout: true,
+		AttachStderr: true,
+	}, &container.HostConfig{
+		Binds:        binds,
+		PortBindings: portBindings,
+		NetworkMode:  container.NetworkMode(opts.Network),
+	}, nil, "")
+	if err != nil {
+		return 0, classify(ctx, err, failure.General)
+	}
+	ui.Log("Created container %s", created.ID)
+	defer removeContainer(cli, created.ID)
+
+	attached, err := cli.ContainerAttach(ctx, created.ID, types.ContainerAttachOptions{Stream: true, Stdout: true, Stderr: true})
+	if err != nil {
+		return 0, classify(ctx, err, failure.General)
+	}
+	defer attached.Close()
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = ui.Stdout()
+	}
+	if stderr == nil {
+		stderr = ui.Stderr()
+	}
+	streamed := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, attached.Reader)
+		streamed <- err
+	}()
+
+	if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return 0, classify(ctx, err, failure.General)
+	}
+
+	go forwardSignals(cli, created.ID, opts.Signals)
+
+	code, err := cli.ContainerWait(ctx, created.ID)
+	if err != nil {
+		return 0, classify(ctx, err, failure.General)
+	}
+	// the output may still be in transit when the container exits
+	select {
+	case <-streamed:
+	case <-time.After(time.Second):
+	}
+
+	return code, nil
+}
+
+// forwardSignals passes signals on to a container. The first signal is forwarded as is, any further one kills the container.
+func forwardSignals(cli *client.Client, id string, signals <-chan os.Signal) {
+	if signals == nil {
+		return
+	}
+
+	forwarded := false
+	for sig := range signals {
+		name := "KILL"
+		if s, ok := sig.(syscall.Signal); ok && !forwarded {
+			name = fmt.Sprint(int(s))
+		}
+		forwarded = true
+
+		ui.Log("Sending signal %s to container %s", name, id)
+		if err := cli.ContainerKill(context.Background(), id, name); err != nil {
+			ui.Log(err.Error())
+		}
+	}
+}
+
+// volumeBinds resolves relative and home directory host paths of volumes, which the daemon requires to be absolute
+func volumeBinds(volumes []string) ([]string, error) {
+	binds := make([]string, len(volumes))
+	for i, volume := range volumes {
+		parts := strings.SplitN(volume, ":", 2)
+		source := parts[0]
+		if len(parts) == 1 || !(strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~")) {
+			binds[i] = volume
+			continue
+		}
+
+		source, err := homedir.Expand(source)
+		if err == nil {
+			source, err = filepath.Abs(source)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid volume %s: %w", volume, err)
+		}
+		binds[i] = source + ":" + parts[1]
+	}
+	return binds, nil
+}
+
+// removeContainer removes a container together with its anonymous volumes
+func removeContainer(cli *client.Client, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	if err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true}); err != nil {
+		ui.Log(err.Error())
+		ui.Warning("Failed removing container %s", id)
+		return
+	}
+	ui.Log("Removed container %s", id)
+}
@@ -0,0 +1,76 @@
+package imgtools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/docker/engine-api/client"
+	"github.com/mitchellh/go-homedir"
+)
+
+func TestVolumeBinds(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binds, err := volumeBinds([]string{"./data:/data", "../cache:/cache:ro", "~/.m2:/root/.m2", "/abs:/abs", "named:/named", "/anonymous"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(wd, "data") + ":/data",
+		filepath.Join(filepath.Dir(wd), "cache") + ":/cache:ro",
+		filepath.Join(home, ".m2") + ":/root/.m2",
+		"/abs:/abs",
+		"named:/named",
+		"/anonymous",
+	}
+	if !reflect.DeepEqual(binds, want) {
+		t.Errorf("volumeBinds() = %v, want %v", binds, want)
+	}
+}
+
+func TestForwardSignals(t *testing.T) {
+	var mu sync.Mutex
+	var kills []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/containers/abc/kill") {
+			mu.Lock()
+			kills = append(kills, r.URL.Query().Get("signal"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer daemon.Close()
+
+	cli, err := client.NewClient("tcp://"+strings.TrimPrefix(daemon.URL, "http://"), "1.23", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signals := make(chan os.Signal, 3)
+	signals <- syscall.SIGINT
+	signals <- syscall.SIGTERM
+	signals <- syscall.SIGINT
+	close(signals)
+	forwardSignals(cli, "abc", signals)
+
+	// the first signal is passed on as is, any further one kills the container
+	if want := []string{"2", "KILL", "KILL"}; !reflect.DeepEqual(kills, want) {
+		t.Errorf("kill signals = %v, want %v", kills, want)
+	}
+}
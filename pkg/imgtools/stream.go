@@ -0,0 +1,172 @@
+package imgtools
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelriegr/draide/pkg/ui"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/term"
+)
+
+var (
+	stepPattern         = regexp.MustCompile(`^Step (\d+)/\d+ : (.*)$`)
+	builtPattern        = regexp.MustCompile(`^Successfully built ([0-9a-f]+)`)
+	pushedDigestPattern = regexp.MustCompile(`digest: (sha256:[0-9a-f]{64})`)
+	runningInPattern    = regexp.MustCompile(`^ ---> Running in ([0-9a-f]+)`)
+	usingCachePattern   = regexp.MustCompile(`^ ---> Using cache$`)
+	layerPattern        = regexp.MustCompile(`^ ---> ([0-9a-f]{12,64})$`)
+)
+
+// streamSummary collects the results of a Docker daemon message stream
+type streamSummary struct {
+	ImageID string
+	Digest  string
+	// Step and Instruction describe the build step which was executed last
+	Step        int
+	Instruction string
+	// Containers lists the intermediate containers the daemon started for build steps
+	Containers []string
+	// Steps lists the executed build steps in order
+	Steps []*BuildStep
+
+	partial []byte
+}
+
+// Write parses complete messages of the stream, making the summary usable as target of an io.TeeReader
+func (s *streamSummary) Write(p []byte) (int, error) {
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		var msg jsonmessage.JSONMessage
+		if err := json.Unmarshal(s.partial[:i], &msg); err == nil {
+			s.record(msg)
+		}
+		s.partial = s.partial[i+1:]
+	}
+}
+
+func (s *streamSummary) record(msg jsonmessage.JSONMessage) {
+	for _, line := range strings.Split(msg.Stream, "\n") {
+		s.recordLine(strings.TrimRight(line, "\r"))
+	}
+	if match := pushedDigestPattern.FindStringSubmatch(msg.Status); match != nil {
+		s.Digest = match[1]
+	}
+
+	if msg.Aux != nil {
+		var aux struct {
+			ID     string
+			Digest string
+		}
+		if err := json.Unmarshal(*msg.Aux, &aux); err == nil {
+			if aux.ID != "" {
+				s.ImageID = aux.ID
+			}
+			if aux.Digest != "" {
+				s.Digest = aux.Digest
+			}
+		}
+	}
+}
+
+// recordLine parses a line of the build output
+func (s *streamSummary) recordLine(line string) {
+	if match := stepPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+		s.Step, _ = strconv.Atoi(match[1])
+		s.Instruction = match[2]
+		s.finish()
+		s.Steps = append(s.Steps, &BuildStep{Number: s.Step, Instruction: s.Instruction, started: time.Now()})
+		return
+	}
+	if match := builtPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil && s.ImageID == "" {
+		s.ImageID = match[1]
+	}
+	if match := runningInPattern.FindStringSubmatch(line); match != nil {
+		s.Containers = append(s.Containers, match[1])
+	}
+
+	if len(s.Steps) == 0 {
+		return
+	}
+	step := s.Steps[len(s.Steps)-1]
+	if usingCachePattern.MatchString(line) {
+		step.Cached = true
+	}
+	// the layer ID is the last one reported for the step
+	if match := layerPattern.FindStringSubmatch(line); match != nil {
+		step.LayerID = match[1]
+	}
+}
+
+// finish ends the timing of the running build step, if any
+func (s *streamSummary) finish() {
+	if len(s.Steps) == 0 {
+		return
+	}
+	if last := s.Steps[len(s.Steps)-1]; last.Duration == 0 {
+		last.Duration = time.Since(last.started)
+	}
+}
+
+// displayStream renders the message stream of the Docker daemon, either as terminal output or as JSON log events.
+// Unless render is set, messages are only parsed and errors are left to the caller.
+// It returns a summary of the stream and the error reported by the daemon, if any.
+func displayStream(stream io.Reader, render bool) (*streamSummary, error) {
+	summary := &streamSummary{}
+	defer summary.finish()
+
+	if ui.IsQuiet() || (!render && !ui.IsJSONLog()) {
+		err := jsonmessage.DisplayJSONMessagesStream(io.TeeReader(stream, summary), ioutil.Discard, 0, false, nil)
+		return summary, err
+	}
+	if !ui.IsJSONLog() {
+		termFd, isTerm := term.GetFdInfo(ui.Stdout())
+		err := jsonmessage.DisplayJSONMessagesStream(io.TeeReader(stream, summary), ui.NewRedactingWriter(ui.Stdout()), termFd, isTerm, nil)
+		return summary, err
+	}
+
+	decoder := json.NewDecoder(stream)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return summary, nil
+		} else if err != nil {
+			return summary, err
+		}
+
+		var msg jsonmessage.JSONMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return summary, err
+		}
+		summary.record(msg)
+
+		message := msg.Stream
+		if message == "" {
+			message = msg.Status
+			if msg.ID != "" {
+				message = msg.ID + ": " + message
+			}
+		}
+		if msg.Error != nil {
+			message = msg.Error.Message
+		}
+		if render || msg.Error != nil {
+			ui.Daemon(message, msg.Error != nil, raw)
+		}
+
+		if msg.Error != nil {
+			return summary, msg.Error
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package imgtools
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDisplayStreamContainers checks that intermediate containers are found without rendering the stream,
+// as they are removed when a build is cancelled in the default, non-verbose mode
+func TestDisplayStreamContainers(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"stream":"Step 1/2 : FROM alpine\n"}`,
+		`{"stream":" ---> a24bb4013296\n"}`,
+		`{"stream":"Step 2/2 : RUN sleep 60\n"}`,
+		`{"stream":" ---> Running in 3f4e5d6c7b8a\n"}`,
+	}, "\r\n") + "\r\n"
+
+	summary, err := displayStream(strings.NewReader(stream), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Containers) != 1 || summary.Containers[0] != "3f4e5d6c7b8a" {
+		t.Errorf("Containers = %v, want [3f4e5d6c7b8a]", summary.Containers)
+	}
+	if summary.Step != 2 {
+		t.Errorf("Step = %d, want 2", summary.Step)
+	}
+}
+
+// buildOutput is the message stream of a build recorded from a Docker daemon. The second build step is cached, the
+// Successfully built line is split across two messages and the aux message reports the image ID.
+var buildOutput = strings.Join([]string{
+	`{"stream":"Step 1/4 : FROM alpine:3.12"}`,
+	`{"stream":"\n"}`,
+	`{"stream":" ---\u003e a24bb4013296\n"}`,
+	`{"stream":"Step 2/4 : COPY go.mod go.sum ./"}`,
+	`{"stream":"\n"}`,
+	`{"stream":" ---\u003e Using cache\n"}`,
+	`{"stream":" ---\u003e 5c6f8b9d0e1f\n"}`,
+	`{"stream":"Step 3/4 : RUN go build -o /app ."}`,
+	`{"stream":"\n"}`,
+	`{"stream":" ---\u003e Running in 3f4e5d6c7b8a\n"}`,
+	`{"stream":"go: downloading github.com/spf13/cobra v1.0.0\n"}`,
+	`{"stream":"Removing intermediate container 3f4e5d6c7b8a\n"}`,
+	`{"stream":" ---\u003e 9a8b7c6d5e4f\n"}`,
+	`{"stream":"Step 4/4 : CMD [\"/app\"]"}`,
+	`{"stream":"\n"}`,
+	`{"stream":" ---\u003e Running in 0a1b2c3d4e5f\n"}`,
+	`{"stream":"Removing intermediate container 0a1b2c3d4e5f\n"}`,
+	`{"stream":" ---\u003e 1f2e3d4c5b6a\n"}`,
+	`{"aux":{"ID":"sha256:1f2e3d4c5b6a7980"}}`,
+	`{"stream":"Successfully built 1f2e3d4c5b6a\nSuccessfully tagged app:latest\n"}`,
+}, "\r\n") + "\r\n"
+
+func TestDisplayStreamSteps(t *testing.T) {
+	summary, err := displayStream(strings.NewReader(buildOutput), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []BuildStep{
+		{Number: 1, Instruction: "FROM alpine:3.12", LayerID: "a24bb4013296"},
+		{Number: 2, Instruction: "COPY go.mod go.sum ./", Cached: true, LayerID: "5c6f8b9d0e1f"},
+		{Number: 3, Instruction: "RUN go build -o /app .", LayerID: "9a8b7c6d5e4f"},
+		{Number: 4, Instruction: `CMD ["/app"]`, LayerID: "1f2e3d4c5b6a"},
+	}
+	if len(summary.Steps) != len(want) {
+		t.Fatalf("Steps = %d, want %d", len(summary.Steps), len(want))
+	}
+	for i, step := range summary.Steps {
+		got := BuildStep{Number: step.Number, Instruction: step.Instruction, Cached: step.Cached, LayerID: step.LayerID}
+		if got != want[i] {
+			t.Errorf("Steps[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if summary.ImageID != "sha256:1f2e3d4c5b6a7980" {
+		t.Errorf("ImageID = %s, want the ID of the aux message", summary.ImageID)
+	}
+	if strings.Join(summary.Containers, ",") != "3f4e5d6c7b8a,0a1b2c3d4e5f" {
+		t.Errorf("Containers = %v", summary.Containers)
+	}
+}
+
+func TestRecordLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		step   int
+		cached bool
+		layer  string
+	}{
+		{line: "Step 12/20 : RUN make", step: 12},
+		{line: "  Step 3/4 : ENV A=1  ", step: 3},
+		{line: " ---> Using cache", step: 1, cached: true},
+		{line: "  ---> Using cache", step: 1},
+		{line: " ---> Using cache now", step: 1},
+		{line: " ---> 5c6f8b9d0e1f", step: 1, layer: "5c6f8b9d0e1f"},
+		{line: " ---> Running in 3f4e5d6c7b8a", step: 1},
+		{line: "Step x/4 : RUN make", step: 1},
+		{line: "Using cache", step: 1},
+	}
+	for _, tt := range tests {
+		s := &streamSummary{}
+		s.recordLine("Step 1/4 : FROM alpine")
+		s.recordLine(tt.line)
+
+		last := s.Steps[len(s.Steps)-1]
+		if last.Number != tt.step || last.Cached != tt.cached || last.LayerID != tt.layer {
+			t.Errorf("recordLine(%q) = step %d, cached %v, layer %q, want %d, %v, %q", tt.line, last.Number, last.Cached, last.LayerID, tt.step, tt.cached, tt.layer)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package imgtools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/docker/engine-api/client"
+)
+
+// Exists reports whether an image is available in the local Docker engine
+func Exists(ctx context.Context, imageName string) (bool, error) {
+	cli, err := newClient()
+	if err != nil {
+		return false, err
+	}
+
+	_, _, err = cli.ImageInspectWithRaw(ctx, imageName, false)
+	if err == client.ErrConnectionFailed || ctx.Err() != nil {
+		return false, classify(ctx, err, failure.General)
+	}
+	return err == nil, nil
+}
+
+// Tag adds a new repository name to a local image
+func Tag(ctx context.Context, source string, target string) error {
+	cli, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	err = cli.ImageTag(ctx, source, target)
+	if err != nil {
+		return classify(ctx, fmt.Errorf("failed tagging image %s as %s: %w", source, target, err), failure.General)
+	}
+	return nil
+}
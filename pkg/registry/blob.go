@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// BlobExists reports whether a blob is already present in a repository
+func (c *Client) BlobExists(repository string, digest string) (bool, error) {
+	req, err := http.NewRequest(http.MethodHead, c.URL("/v2/"+repository+"/blobs/"+digest), nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.Do(req, pushScope(repository))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, responseError(res, "failed checking blob "+digest)
+	}
+}
+
+// MountBlob links a blob of another repository of the same registry without transferring any data.
+// It returns false if the registry refused to mount and the blob has to be uploaded instead.
+func (c *Client) MountBlob(repository string, digest string, fromRepository string) (bool, error) {
+	query := url.Values{"mount": {digest}, "from": {fromRepository}}
+	req, err := http.NewRequest(http.MethodPost, c.URL("/v2/"+repository+"/blobs/uploads/?"+query.Encode()), nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.Do(req, pushScope(repository)+" "+pullScope(fromRepository))
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	return res.StatusCode == http.StatusCreated, nil
+}
+
+// GetBlob opens a blob for reading. The returned size is -1 if the registry does not announce it.
+func (c *Client) GetBlob(repository string, digest string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, c.URL("/v2/"+repository+"/blobs/"+digest), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res, err := c.Do(req, pullScope(repository))
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, responseError(res, "failed fetching blob "+digest)
+	}
+
+	return res.Body, res.ContentLength, nil
+}
+
+// UploadBlob uploads a blob in a single request. getBody opens the content once more, so that the upload can be sent
+// again after an authentication challenge.
+func (c *Client) UploadBlob(repository string, digest string, content io.Reader, size int64, getBody func() (io.ReadCloser, error)) error {
+	req, err := http.NewRequest(http.MethodPost, c.URL("/v2/"+repository+"/blobs/uploads/"), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Do(req, pushScope(repository))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusAccepted {
+		defer res.Body.Close()
+		return responseError(res, "failed starting upload of blob "+digest)
+	}
+	res.Body.Close()
+
+	location, err := res.Request.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	req, err = http.NewRequest(http.MethodPut, location.String(), content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.GetBody = getBody
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err = c.Do(req, pushScope(repository))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return responseError(res, "failed uploading blob "+digest)
+	}
+
+	return nil
+}
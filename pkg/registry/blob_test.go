@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadBlobAfterChallenge(t *testing.T) {
+	const blob = "layer content"
+	uploads := []string{}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token":"abc"}`)
+	})
+	mux.HandleFunc("/v2/app/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/upload/1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/upload/1", func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		// the upload location asks for a token on its own, after the body has been sent once
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		uploads = append(uploads, string(content))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	opened := 0
+	getBody := func() (io.ReadCloser, error) {
+		opened++
+		return ioutil.NopCloser(strings.NewReader(blob)), nil
+	}
+	client := NewClient(strings.TrimPrefix(srv.URL, "http://"), Credentials{})
+	// the content is not a type http.NewRequest knows how to rewind
+	content := io.MultiReader(strings.NewReader(blob))
+	if err := client.UploadBlob("app", "sha256:c", content, int64(len(blob)), getBody); err != nil {
+		t.Fatal(err)
+	}
+
+	if opened != 1 || len(uploads) != 1 || uploads[0] != blob {
+		t.Errorf("opened %d times, uploads = %q", opened, uploads)
+	}
+}
+
+func TestUploadBlobErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":[{"code":"DENIED","message":"quota exceeded"}]}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient(strings.TrimPrefix(srv.URL, "http://"), Credentials{})
+	err := client.UploadBlob("app", "sha256:c", strings.NewReader("x"), 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("UploadBlob() error = %v, want the body of the response", err)
+	}
+}
@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/failure"
+
+	"github.com/spf13/viper"
+)
+
+// ErrUnauthorized is returned if the registry rejects the supplied credentials
+var ErrUnauthorized = failure.New(failure.AuthRejected, "registry rejected credentials")
+
+// Credentials to authenticate against a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to the Docker registry HTTP API v2 and handles the basic and bearer token authentication flows
+type Client struct {
+	Registry    string
+	Credentials Credentials
+
+	http       *http.Client
+	authHeader map[string]string
+}
+
+// NewClient creates a registry API client
+func NewClient(registry string, creds Credentials) *Client {
+	return &Client{
+		Registry:    NormalizeRegistry(registry),
+		Credentials: creds,
+		http:        &http.Client{},
+		authHeader:  map[string]string{},
+	}
+}
+
+// URL returns the absolute URL of an API path
+func (c *Client) URL(path string) string {
+	scheme := "https"
+	if isLocalhost(c.Registry) || isInsecure(c.Registry) {
+		scheme = "http"
+	}
+	return scheme + "://" + apiHost(c.Registry) + path
+}
+
+// Ping verifies that the registry is reachable and accepts the client's credentials
+func (c *Client) Ping() error {
+	req, err := http.NewRequest(http.MethodGet, c.URL("/v2/"), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Do(req, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	default:
+		return fmt.Errorf("unexpected response from %s: %s", c.Registry, res.Status)
+	}
+}
+
+// Do sends an API request and transparently answers authentication challenges.
+// The scope (e.g. repository:namespace/name:pull,push) is requested when a bearer token is required, multiple scopes are separated by spaces.
+func (c *Client) Do(req *http.Request, scope string) (*http.Response, error) {
+	if header, ok := c.authHeader[scope]; ok {
+		req.Header.Set("Authorization", header)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	challenge := res.Header.Get("WWW-Authenticate")
+	res.Body.Close()
+
+	header, err := c.authorize(challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	c.authHeader[scope] = header
+
+	retry, err := rewind(req)
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", header)
+
+	return c.http.Do(retry)
+}
+
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize computes the Authorization header answering a WWW-Authenticate challenge
+func (c *Client) authorize(challenge string, scope string) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	params := map[string]string{}
+	for _, m := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+
+	switch scheme {
+	case "basic":
+		if c.Credentials.Username == "" {
+			return "", ErrUnauthorized
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(c.Credentials.Username, c.Credentials.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		token, err := c.fetchToken(params["realm"], params["service"], stringOr(scope, params["scope"]))
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication scheme in challenge: %s", challenge)
+	}
+}
+
+// fetchToken requests a bearer token from the authorization server named in the challenge
+func (c *Client) fetchToken(realm string, service string, scope string) (string, error) {
+	if realm == "" {
+		return "", errors.New("bearer challenge without realm")
+	}
+
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	for _, s := range strings.Fields(scope) {
+		query.Add("scope", s)
+	}
+	if c.Credentials.Username != "" {
+		query.Set("account", c.Credentials.Username)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.Credentials.Username != "" {
+		req.SetBasicAuth(c.Credentials.Username, c.Credentials.Password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return "", ErrUnauthorized
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed fetching token from %s: %s", realm, res.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	return stringOr(body.Token, body.AccessToken), nil
+}
+
+// rewind returns a copy of the request with a fresh body so it can be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("cannot resend request body after authentication challenge")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retry.Body = body
+	return retry, nil
+}
+
+// isInsecure reports whether the registry is listed in the `insecureRegistries` configuration and must be accessed via plain http
+func isInsecure(registry string) bool {
+	for _, r := range viper.GetStringSlice("insecureRegistries") {
+		if NormalizeRegistry(r) == registry {
+			return true
+		}
+	}
+	return false
+}
+
+func stringOr(value string, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testUsername = "alice"
+	testPassword = "s3cret"
+)
+
+// newBasicRegistry starts a registry stand-in protecting /v2/ with basic auth, as a registry with htpasswd auth does
+func newBasicRegistry(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != testUsername || password != testPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Registry Realm"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// newBearerRegistry starts a registry stand-in which requires a bearer token issued by its token endpoint
+func newBearerRegistry(t *testing.T) string {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != testUsername || password != testPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("service") != "test-registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"token":"abc"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestPing(t *testing.T) {
+	registries := map[string]string{
+		"basic":  newBasicRegistry(t),
+		"bearer": newBearerRegistry(t),
+	}
+	for name, registry := range registries {
+		t.Run(name, func(t *testing.T) {
+			if err := NewClient(registry, Credentials{Username: testUsername, Password: testPassword}).Ping(); err != nil {
+				t.Errorf("Ping() with valid credentials = %v", err)
+			}
+			if err := NewClient(registry, Credentials{Username: testUsername, Password: "wrong"}).Ping(); err != ErrUnauthorized {
+				t.Errorf("Ping() with invalid credentials = %v, want ErrUnauthorized", err)
+			}
+			if err := NewClient(registry, Credentials{}).Ping(); err != ErrUnauthorized {
+				t.Errorf("Ping() without credentials = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	if err := NewClient(strings.TrimPrefix(srv.URL, "http://"), Credentials{}).Ping(); err == nil || err == ErrUnauthorized {
+		t.Errorf("Ping() of closed server = %v, want a connection error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+)
+
+// Copy transfers an image blob by blob from one registry location to another without pulling it into a Docker engine.
+// The manifest is uploaded unmodified, so the digest of the copy is identical to the source. It returns the digest of the copied manifest.
+func Copy(src *Client, srcRef Reference, dst *Client, dstRef Reference) (string, error) {
+	manifest, err := src.GetManifest(srcRef.Repository, srcRef.Ref())
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyReferences(src, srcRef.Repository, dst, dstRef.Repository, manifest); err != nil {
+		return "", err
+	}
+
+	if err := dst.PutManifest(dstRef.Repository, dstRef.Ref(), manifest); err != nil {
+		return "", err
+	}
+
+	return manifest.Digest, nil
+}
+
+// copyReferences copies all manifests and blobs referenced by a manifest
+func copyReferences(src *Client, srcRepository string, dst *Client, dstRepository string, manifest *Manifest) error {
+	refs, err := manifest.References()
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if manifest.IsList() {
+			child, err := src.GetManifest(srcRepository, ref.Digest)
+			if err != nil {
+				return err
+			}
+			if err := copyReferences(src, srcRepository, dst, dstRepository, child); err != nil {
+				return err
+			}
+			if err := dst.PutManifest(dstRepository, ref.Digest, child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := copyBlob(src, srcRepository, dst, dstRepository, ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyBlob(src *Client, srcRepository string, dst *Client, dstRepository string, ref Descriptor) error {
+	digest := ref.Digest
+	exists, err := dst.BlobExists(dstRepository, digest)
+	if err != nil || exists {
+		return err
+	}
+
+	if src.Registry == dst.Registry {
+		mounted, err := dst.MountBlob(dstRepository, digest, srcRepository)
+		if err != nil || mounted {
+			return err
+		}
+	}
+
+	blob, size, err := src.GetBlob(srcRepository, digest)
+	if err != nil {
+		return fmt.Errorf("failed fetching blob %s: %v", digest, err)
+	}
+	defer blob.Close()
+	// the length of chunked responses is unknown, while uploads require it
+	if size < 0 {
+		size = ref.Size
+	}
+
+	// the blob is fetched once more if the upload has to be repeated after an authentication challenge
+	getBody := func() (io.ReadCloser, error) {
+		blob, _, err := src.GetBlob(srcRepository, digest)
+		return blob, err
+	}
+	return dst.UploadBlob(dstRepository, digest, blob, size, getBody)
+}
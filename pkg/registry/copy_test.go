@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCopyChunkedBlob(t *testing.T) {
+	const blob = "layer content"
+	manifest := fmt.Sprintf(`{"mediaType":%q,"config":{"digest":"sha256:c","size":%d},"layers":[]}`, MediaTypeManifest, len(blob))
+
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/staging/app/manifests/1.0":
+			w.Header().Set("Content-Type", MediaTypeManifest)
+			w.Header().Set("Docker-Content-Digest", "sha256:m")
+			fmt.Fprint(w, manifest)
+		case "/v2/staging/app/blobs/sha256:c":
+			// flushing before writing makes the response chunked, so its length is unknown
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer src.Close()
+
+	var mu sync.Mutex
+	uploaded := map[string]string{}
+	var uploadLength int64
+	dst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/prod/app/blobs/uploads/":
+			w.Header().Set("Location", "/upload/1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/upload/1":
+			uploadLength = r.ContentLength
+			content, _ := ioutil.ReadAll(r.Body)
+			uploaded[r.URL.Query().Get("digest")] = string(content)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/prod/app/manifests/1.0":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer dst.Close()
+
+	srcClient := NewClient(strings.TrimPrefix(src.URL, "http://"), Credentials{})
+	dstClient := NewClient(strings.TrimPrefix(dst.URL, "http://"), Credentials{})
+	digest, err := Copy(srcClient, Reference{Repository: "staging/app", Tag: "1.0"}, dstClient, Reference{Repository: "prod/app", Tag: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if digest != "sha256:m" {
+		t.Errorf("digest = %s", digest)
+	}
+	if uploaded["sha256:c"] != blob {
+		t.Errorf("uploaded = %v", uploaded)
+	}
+	if uploadLength != int64(len(blob)) {
+		t.Errorf("upload Content-Length = %d, want the size of the descriptor %d", uploadLength, len(blob))
+	}
+}
@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Manifest media types supported for copying
+const (
+	MediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+)
+
+var acceptedManifestTypes = []string{MediaTypeManifestList, MediaTypeOCIIndex, MediaTypeManifest, MediaTypeOCIManifest}
+
+// ErrNotFound is returned if a manifest or blob does not exist in the registry
+var ErrNotFound = errors.New("not found in registry")
+
+// Manifest is a raw image manifest or manifest list as stored in the registry
+type Manifest struct {
+	Content   []byte
+	MediaType string
+	Digest    string
+}
+
+// Descriptor references a blob or manifest by digest
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifestContent struct {
+	MediaType string       `json:"mediaType"`
+	Config    Descriptor   `json:"config"`
+	Layers    []Descriptor `json:"layers"`
+	Manifests []Descriptor `json:"manifests"`
+}
+
+// IsList reports whether the manifest references other manifests (multi-platform image)
+func (m *Manifest) IsList() bool {
+	return m.MediaType == MediaTypeManifestList || m.MediaType == MediaTypeOCIIndex
+}
+
+// References returns the manifests of a list or the config and layer blobs of an image manifest
+func (m *Manifest) References() ([]Descriptor, error) {
+	var content manifestContent
+	if err := json.Unmarshal(m.Content, &content); err != nil {
+		return nil, err
+	}
+	if m.IsList() {
+		return content.Manifests, nil
+	}
+	return append([]Descriptor{content.Config}, content.Layers...), nil
+}
+
+// GetManifest fetches the manifest of a repository by tag or digest
+func (c *Client) GetManifest(repository string, ref string) (*Manifest, error) {
+	res, err := c.manifestRequest(http.MethodGet, repository, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType := strings.Split(res.Header.Get("Content-Type"), ";")[0]
+	switch mediaType {
+	case MediaTypeManifest, MediaTypeManifestList, MediaTypeOCIManifest, MediaTypeOCIIndex:
+	default:
+		return nil, fmt.Errorf("unsupported manifest type %s for %s:%s", mediaType, repository, ref)
+	}
+
+	return &Manifest{
+		Content:   content,
+		MediaType: mediaType,
+		Digest:    res.Header.Get("Docker-Content-Digest"),
+	}, nil
+}
+
+// HeadManifest returns the digest of a manifest or ErrNotFound if it does not exist
+func (c *Client) HeadManifest(repository string, ref string) (string, error) {
+	res, err := c.manifestRequest(http.MethodHead, repository, ref)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	return res.Header.Get("Docker-Content-Digest"), nil
+}
+
+// PutManifest uploads a manifest under the given tag or digest
+func (c *Client) PutManifest(repository string, ref string, manifest *Manifest) error {
+	req, err := http.NewRequest(http.MethodPut, c.URL("/v2/"+repository+"/manifests/"+ref), bytes.NewReader(manifest.Content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", manifest.MediaType)
+
+	res, err := c.Do(req, pushScope(repository))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return responseError(res, "failed uploading manifest "+repository+":"+ref)
+	}
+	return nil
+}
+
+func (c *Client) manifestRequest(method string, repository string, ref string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.URL("/v2/"+repository+"/manifests/"+ref), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(acceptedManifestTypes, ", "))
+
+	res, err := c.Do(req, pullScope(repository))
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		res.Body.Close()
+		return nil, ErrUnauthorized
+	default:
+		defer res.Body.Close()
+		return nil, responseError(res, "failed fetching manifest "+repository+":"+ref)
+	}
+}
+
+func pullScope(repository string) string {
+	return "repository:" + repository + ":pull"
+}
+
+func pushScope(repository string) string {
+	return "repository:" + repository + ":pull,push"
+}
+
+func responseError(res *http.Response, msg string) error {
+	body, _ := ioutil.ReadAll(res.Body)
+	return fmt.Errorf("%s: %s %s", msg, res.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"strings"
+)
+
+// DockerHub is the canonical name of the default registry
+const DockerHub = "docker.io"
+
+// Reference contains the components of an image reference
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference splits an image reference like registry:5000/namespace/name:tag into its components
+func ParseReference(name string) Reference {
+	ref := Reference{Registry: DockerHub}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i+1:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 {
+		domain := name[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			ref.Registry = domain
+			name = name[i+1:]
+		}
+	}
+
+	if ref.Registry == "index.docker.io" || ref.Registry == "registry-1.docker.io" {
+		ref.Registry = DockerHub
+	}
+	if ref.Registry == DockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	ref.Repository = name
+
+	return ref
+}
+
+// String returns the fully qualified reference
+func (r Reference) String() string {
+	name := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		name += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		name += "@" + r.Digest
+	}
+	return name
+}
+
+// Ref returns the tag or, if set, the digest used to address the manifest of the reference
+func (r Reference) Ref() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// NormalizeRegistry returns the canonical host name of a registry address, stripping schemes and paths
+func NormalizeRegistry(registry string) string {
+	if registry == "" {
+		return DockerHub
+	}
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return DockerHub
+	}
+	return registry
+}
+
+// apiHost returns the host serving the registry API
+func apiHost(registry string) string {
+	if registry == DockerHub {
+		return "registry-1.docker.io"
+	}
+	return registry
+}
+
+// isLocalhost reports whether the registry is served from the local machine, in which case plain http is used
+func isLocalhost(registry string) bool {
+	host := registry
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return host == "localhost" || host == "[::1]" || strings.HasPrefix(host, "127.")
+}
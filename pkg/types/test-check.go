@@ -0,0 +1,19 @@
+package types
+
+// TestCheck is a check of the test section run against a built image. Exactly one kind of check is set.
+type TestCheck struct {
+	Name string
+	// Command runs the image with the given command and asserts on ExitCode and, if set, the Stdout regular expression.
+	// The first item replaces the entrypoint of the image, so that commands run the same regardless of the entrypoint.
+	Command  []string
+	ExitCode int
+	Stdout   string
+	// File asserts that the path exists in the image
+	File string
+	// ExposedPorts asserts that the image exposes the ports, such as 8080 or 53/udp
+	ExposedPorts []string
+	// NonRoot asserts that the image runs as a user other than root
+	NonRoot bool
+	// Labels asserts that the image has labels with the given names
+	Labels []string
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Supported CI providers
+const (
+	CIGitHub = "github"
+	CIGitLab = "gitlab"
+)
+
+var openGroups = []string{}
+var groupCount = 0
+
+// CIProvider returns the CI provider output is adapted to, empty if none.
+// The provider is detected from the environment unless the `ci` setting names one explicitly or is set to none.
+func CIProvider() string {
+	switch provider := viper.GetString("ci"); provider {
+	case "", "auto":
+		if os.Getenv("GITHUB_ACTIONS") == "true" {
+			return CIGitHub
+		}
+		if os.Getenv("GITLAB_CI") == "true" {
+			return CIGitLab
+		}
+		return ""
+	case "none":
+		return ""
+	default:
+		return provider
+	}
+}
+
+// StartGroup starts a collapsible section of CI output. Groups are not nested, starting a group ends the open one.
+// Like annotations, group markers are diagnostics and written to stderr, which CI providers render in the same log.
+func StartGroup(title string) {
+	EndGroup()
+	if IsJSONLog() || IsQuiet() {
+		return
+	}
+
+	title = Redact(title)
+	switch CIProvider() {
+	case CIGitHub:
+		fmt.Fprintln(stderr, "::group::"+escapeCommandData(title))
+		openGroups = append(openGroups, title)
+	case CIGitLab:
+		groupCount++
+		name := fmt.Sprintf("draide_%d", groupCount)
+		fmt.Fprintf(stderr, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", time.Now().Unix(), name, title)
+		openGroups = append(openGroups, name)
+	}
+}
+
+// EndGroup ends the open collapsible section of CI output, if any
+func EndGroup() {
+	if len(openGroups) == 0 {
+		return
+	}
+	name := openGroups[len(openGroups)-1]
+	openGroups = openGroups[:len(openGroups)-1]
+
+	switch CIProvider() {
+	case CIGitHub:
+		fmt.Fprintln(stderr, "::endgroup::")
+	case CIGitLab:
+		fmt.Fprintf(stderr, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), name)
+	}
+}
+
+// ErrorAt reports an error at a line of a file. On GitHub Actions the error is shown as annotation of the file.
+// A line of 0 refers to the file as a whole.
+func ErrorAt(file string, line int, format string, args ...interface{}) {
+	message := Redact(fmt.Sprintf(format, args...))
+	if CIProvider() != CIGitHub || IsJSONLog() {
+		if line > 0 {
+			Error("%s:%d: %s", file, line, message)
+		} else {
+			Error("%s: %s", file, message)
+		}
+		return
+	}
+
+	properties := "file=" + escapeCommandProperty(workspacePath(file))
+	if line > 0 {
+		properties += fmt.Sprintf(",line=%d", line)
+	}
+	EndGroup()
+	fmt.Fprintf(stderr, "::error %s::%s\n", properties, escapeCommandData(message))
+}
+
+// annotate prints a message as GitHub Actions annotation and reports whether it did
+func annotate(level string, message string) bool {
+	if CIProvider() != CIGitHub || IsJSONLog() {
+		return false
+	}
+	// annotations inside a collapsed group are hard to find
+	EndGroup()
+	fmt.Fprintf(stderr, "::%s::%s\n", level, escapeCommandData(message))
+	return true
+}
+
+// workspacePath returns a path relative to the repository checkout as expected by annotations
+func workspacePath(path string) string {
+	root := os.Getenv("GITHUB_WORKSPACE")
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(abs)
+}
+
+func escapeCommandData(str string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(str)
+}
+
+func escapeCommandProperty(str string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(str)
+}
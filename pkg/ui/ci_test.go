@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupsAndAnnotationsOnStderr(t *testing.T) {
+	out, errOut := withOutput(t, map[string]interface{}{"ci": CIGitHub})
+
+	StartGroup("Building app")
+	Warning("Dockerfile not pinned")
+	Result("app:1")
+	EndGroup()
+
+	if out.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing", out.String())
+	}
+	want := "::group::Building app\n::endgroup::\n::warning::Dockerfile not pinned\n"
+	if errOut.String() != want {
+		t.Errorf("stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestGitLabSections(t *testing.T) {
+	_, errOut := withOutput(t, map[string]interface{}{"ci": CIGitLab})
+
+	StartGroup("Pushing app")
+	EndGroup()
+
+	lines := strings.Split(strings.TrimSuffix(errOut.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "section_start:") || !strings.Contains(lines[1], "section_end:") {
+		t.Errorf("stderr = %q, want a section start and end", errOut.String())
+	}
+}
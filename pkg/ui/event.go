@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Event is a log entry in JSON log format
+type Event struct {
+	Level     string `json:"level"`
+	Timestamp string `json:"timestamp"`
+	Phase     string `json:"phase,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Message   string `json:"message"`
+	// Daemon holds the message of the Docker daemon stream this event was created from
+	Daemon json.RawMessage `json:"daemon,omitempty"`
+}
+
+var phase, image, tag string
+
+// IsJSONLog reports whether log output is emitted as JSON events, one per line
+func IsJSONLog() bool {
+	return viper.GetString("logFormat") == "json"
+}
+
+// SetPhase sets the phase attached to subsequent events, such as config, build or push
+func SetPhase(value string) {
+	phase = value
+}
+
+// SetImage sets the image attached to subsequent events and resets the tag
+func SetImage(value string) {
+	image = value
+	tag = ""
+}
+
+// SetTag sets the tag attached to subsequent events
+func SetTag(value string) {
+	tag = value
+}
+
+// Daemon logs a message of the Docker daemon stream. In text log format the daemon stream is rendered by the caller.
+func Daemon(message string, failed bool, raw json.RawMessage) {
+	level := "info"
+	if failed {
+		level = "error"
+	}
+	redacted, err := RedactJSON(raw)
+	if err != nil {
+		// the message carries the content, which is redacted as plain text
+		redacted = nil
+	}
+	emit(level, strings.TrimSpace(message), redacted)
+}
+
+func emit(level string, message string, daemon json.RawMessage) {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(Event{
+		Level:     level,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Phase:     phase,
+		Image:     image,
+		Tag:       tag,
+		Message:   Redact(message),
+		Daemon:    daemon,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "{\"level\":\"error\",\"message\":%q}\n", err.Error())
+		return
+	}
+	stderr.Write(content.Bytes())
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// withOutput captures stdout and stderr with the given settings applied
+func withOutput(t *testing.T, settings map[string]interface{}) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	previousOut, previousErr := stdout, stderr
+	SetOutput(&out, &errOut)
+	for key, value := range settings {
+		viper.Set(key, value)
+	}
+	t.Cleanup(func() {
+		SetOutput(previousOut, previousErr)
+		viper.Reset()
+	})
+	return &out, &errOut
+}
+
+func TestDaemonRedactsEscapedSecrets(t *testing.T) {
+	withSecrets(t, `p"a\ss<&>`)
+	_, errOut := withOutput(t, map[string]interface{}{"logFormat": "json"})
+
+	raw := json.RawMessage(`{"stream":"echo p\"a\\ss\u003c\u0026\u003e","progressDetail":{"current":1}}`)
+	Daemon(`echo p"a\ss<&>`, false, raw)
+
+	var event Event
+	if err := json.Unmarshal(errOut.Bytes(), &event); err != nil {
+		t.Fatalf("invalid event %s: %v", errOut.String(), err)
+	}
+	if event.Message != "echo ******" {
+		t.Errorf("Message = %q", event.Message)
+	}
+	if want := `{"stream":"echo ******","progressDetail":{"current":1}}`; string(event.Daemon) != want {
+		t.Errorf("Daemon = %s, want %s", event.Daemon, want)
+	}
+}
+
+func TestResultInJSONLogFormat(t *testing.T) {
+	out, errOut := withOutput(t, map[string]interface{}{"logFormat": "json"})
+
+	Info("Pushing %s", "app:1")
+	Result("app:1@sha256:abc")
+
+	if out.String() != "app:1@sha256:abc\n" {
+		t.Errorf("stdout = %q, want only the result", out.String())
+	}
+	if !strings.Contains(errOut.String(), `"message":"Pushing app:1"`) {
+		t.Errorf("stderr = %q, want the info event", errOut.String())
+	}
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/docker/pkg/term"
+	au "github.com/logrusorgru/aurora/v3"
+	"github.com/spf13/viper"
+)
+
+var stdout io.Writer = os.Stdout
+var stderr io.Writer = os.Stderr
+
+// SetOutput sets the writers all output is written to. Results and the Docker daemon stream are written to out,
+// diagnostics to errOut. Both may be the same writer, e.g. to capture all output in tests.
+func SetOutput(out io.Writer, errOut io.Writer) {
+	stdout = out
+	stderr = errOut
+}
+
+// Stdout returns the writer results and the Docker daemon stream are written to
+func Stdout() io.Writer {
+	return stdout
+}
+
+// Stderr returns the writer diagnostics are written to
+func Stderr() io.Writer {
+	return stderr
+}
+
+// IsQuiet reports whether output is limited to errors and final results
+func IsQuiet() bool {
+	return viper.GetBool("quiet")
+}
+
+// Result prints a final result of the run, such as a tag or digest, to stdout. It only prints in quiet mode and
+// JSON log format, where diagnostics go to stderr and the results are the only output on stdout meant for scripting.
+func Result(format string, args ...interface{}) {
+	if IsQuiet() || IsJSONLog() {
+		fmt.Fprintln(stdout, fmt.Sprintf(format, args...))
+	}
+}
+
+// IsColorEnabled reports whether diagnostics are coloured. With `color` set to auto, colours are used if
+// stderr is a terminal and neither NO_COLOR is set nor TERM is dumb.
+func IsColorEnabled() bool {
+	switch viper.GetString("color") {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(stderr)
+}
+
+// IsTerminal reports whether a writer is a terminal
+func IsTerminal(w io.Writer) bool {
+	_, isTerm := term.GetFdInfo(w)
+	return isTerm
+}
+
+func colorizer() au.Aurora {
+	return au.NewAurora(IsColorEnabled())
+}
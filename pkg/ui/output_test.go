@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"os"
+	"testing"
+)
+
+func TestQuietMode(t *testing.T) {
+	out, errOut := withOutput(t, map[string]interface{}{"quiet": true, "verbose": true})
+
+	Info("Building %s", "app")
+	Success("Built %s", "app")
+	Warning("Dockerfile not pinned")
+	Log("Created container %s", "abc")
+	Error("Push of %s failed", "app:2")
+	Result("app:1@sha256:abc")
+
+	if out.String() != "app:1@sha256:abc\n" {
+		t.Errorf("stdout = %q, want only the result", out.String())
+	}
+	if errOut.String() != "Push of app:2 failed\n" {
+		t.Errorf("stderr = %q, want only the error", errOut.String())
+	}
+}
+
+func TestResultOnlyInQuietMode(t *testing.T) {
+	out, _ := withOutput(t, nil)
+
+	Result("app:1")
+
+	if out.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing", out.String())
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		noColor string
+		want    bool
+	}{
+		{color: "always", want: true},
+		{color: "always", noColor: "1", want: true},
+		{color: "never", want: false},
+		{color: "auto", noColor: "1", want: false},
+		// the captured output is no terminal
+		{color: "auto", want: false},
+	}
+	for _, test := range tests {
+		withOutput(t, map[string]interface{}{"color": test.color})
+		setEnv(t, "NO_COLOR", test.noColor)
+
+		if got := IsColorEnabled(); got != test.want {
+			t.Errorf("IsColorEnabled() with color %s and NO_COLOR %q = %v, want %v", test.color, test.noColor, got, test.want)
+		}
+	}
+}
+
+func TestColoredOutput(t *testing.T) {
+	_, errOut := withOutput(t, map[string]interface{}{"color": "always"})
+	Error("failed")
+	if errOut.String() != "\x1b[31mfailed\x1b[0m\n" {
+		t.Errorf("stderr = %q, want red text", errOut.String())
+	}
+
+	_, errOut = withOutput(t, map[string]interface{}{"color": "never"})
+	Error("failed")
+	if errOut.String() != "failed\n" {
+		t.Errorf("stderr = %q, want plain text", errOut.String())
+	}
+}
+
+// setEnv sets an environment variable for the duration of a test, unsetting it for an empty value
+func setEnv(t *testing.T, key string, value string) {
+	t.Helper()
+	previous, ok := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Redacted is the placeholder printed instead of a secret value
+const Redacted = "******"
+
+// minSecretLength is the length below which values are not treated as secret, since masking values like "1" or "on"
+// would garble unrelated output. Secrets shorter than this, such as a three-character password, are not masked.
+const minSecretLength = 4
+
+var secrets []string
+
+// AddSecret registers a value which must never appear in any output.
+// Values shorter than minSecretLength are ignored and therefore not masked.
+func AddSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+
+	// replace longer secrets first so that a secret containing another one is masked completely
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Redact masks all registered secret values inside a string
+func Redact(str string) string {
+	for _, s := range secrets {
+		str = strings.ReplaceAll(str, s, Redacted)
+	}
+	return str
+}
+
+// redactingWriter masks registered secrets in everything written to the underlying writer
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter returns a writer masking registered secrets before writing to w.
+// Each write is masked on its own, so a secret split across two writes is not masked.
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RedactValue masks secret values inside the strings of decoded data, such as maps and lists of configuration values.
+// Non-string values are kept, so that the result can be encoded without being garbled by the masking.
+func RedactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return Redact(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = Redact(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = RedactValue(item)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for k, item := range v {
+			redacted[Redact(k)] = Redact(item)
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			redacted[Redact(k)] = RedactValue(item)
+		}
+		return redacted
+	case map[interface{}]interface{}:
+		redacted := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			redacted[RedactValue(k)] = RedactValue(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// RedactJSON masks secret values inside the strings of a JSON document, keeping the order of its keys.
+// Secrets are masked before encoding, so that escaped secrets are masked as well and the document stays valid.
+func RedactJSON(content []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var out bytes.Buffer
+	// containers tracks the open objects and arrays together with the number of keys and values written to them
+	type container struct {
+		object bool
+		count  int
+	}
+	var containers []*container
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			containers = containers[:len(containers)-1]
+			out.WriteRune(rune(delim))
+			continue
+		}
+
+		if n := len(containers); n > 0 {
+			top := containers[n-1]
+			if top.object && top.count%2 == 1 {
+				out.WriteByte(':')
+			} else if top.count > 0 {
+				out.WriteByte(',')
+			}
+			top.count++
+		}
+		switch v := token.(type) {
+		case json.Delim:
+			out.WriteRune(rune(v))
+			containers = append(containers, &container{object: v == '{'})
+		case string:
+			encoded, err := json.Marshal(Redact(v))
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded)
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+	if len(containers) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return out.Bytes(), nil
+}
+
+// MarshalRedacted encodes a value as indented JSON with secret values masked inside its strings
+func MarshalRedacted(value interface{}) ([]byte, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = RedactJSON(content); err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, content, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func withSecrets(t *testing.T, values ...string) {
+	t.Helper()
+	previous := secrets
+	secrets = nil
+	for _, v := range values {
+		AddSecret(v)
+	}
+	t.Cleanup(func() { secrets = previous })
+}
+
+func TestAddSecretSkipsShortValues(t *testing.T) {
+	withSecrets(t, "", "1", "abc", "s3cret")
+
+	if len(secrets) != 1 || secrets[0] != "s3cret" {
+		t.Errorf("secrets = %q, want only s3cret", secrets)
+	}
+}
+
+func TestRedactLongestSecretFirst(t *testing.T) {
+	withSecrets(t, "pass", "password123")
+
+	if got := Redact("a password123 b pass"); got != "a ****** b ******" {
+		t.Errorf("Redact() = %q", got)
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	withSecrets(t, "s3cret")
+
+	var out bytes.Buffer
+	w := NewRedactingWriter(&out)
+	n, err := w.Write([]byte("Step 1/2 : ARG TOKEN=s3cret\n"))
+	if err != nil || n != 28 {
+		t.Errorf("Write() = %d, %v", n, err)
+	}
+	if got := out.String(); got != "Step 1/2 : ARG TOKEN=******\n" {
+		t.Errorf("written = %q", got)
+	}
+}
+
+func TestMarshalRedacted(t *testing.T) {
+	withSecrets(t, `p"a\ss<&>`, "true")
+
+	value := struct {
+		Built    bool              `json:"built"`
+		Count    int               `json:"count"`
+		Password string            `json:"password"`
+		Labels   map[string]string `json:"labels"`
+		Tags     []string          `json:"tags"`
+	}{
+		Built:    true,
+		Count:    1,
+		Password: `p"a\ss<&>`,
+		Labels:   map[string]string{"flag": "true", "token": `x-p"a\ss<&>-y`},
+		Tags:     []string{"a", `p"a\ss<&>`},
+	}
+	content, err := MarshalRedacted(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("invalid JSON %s: %v", content, err)
+	}
+	if decoded["built"] != true || decoded["count"] != 1.0 {
+		t.Errorf("non-string values changed: %s", content)
+	}
+	if decoded["password"] != Redacted {
+		t.Errorf("password = %v", decoded["password"])
+	}
+	labels := decoded["labels"].(map[string]interface{})
+	if labels["flag"] != Redacted || labels["token"] != "x-"+Redacted+"-y" {
+		t.Errorf("labels = %v", labels)
+	}
+	if strings.Contains(string(content), `p\"a`) {
+		t.Errorf("escaped secret not masked: %s", content)
+	}
+	if !strings.HasPrefix(string(content), "{\n  \"built\"") {
+		t.Errorf("key order not kept: %s", content)
+	}
+}
+
+func TestRedactJSONInvalid(t *testing.T) {
+	if _, err := RedactJSON([]byte(`{"a":`)); err == nil {
+		t.Error("expected an error for truncated JSON")
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	withSecrets(t, "hunter2")
+
+	value := map[string]interface{}{
+		"password": "hunter2",
+		"retries":  2,
+		"nested":   map[interface{}]interface{}{"list": []interface{}{"x hunter2", true}},
+	}
+	redacted := RedactValue(value).(map[string]interface{})
+
+	if redacted["password"] != Redacted || redacted["retries"] != 2 {
+		t.Errorf("redacted = %v", redacted)
+	}
+	list := redacted["nested"].(map[interface{}]interface{})["list"].([]interface{})
+	if list[0] != "x "+Redacted || list[1] != true {
+		t.Errorf("list = %v", list)
+	}
+	if value["password"] != "hunter2" {
+		t.Error("input was modified")
+	}
+}
@@ -3,11 +3,16 @@ package ui
 import (
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/marcelriegr/draide/pkg/failure"
 
 	au "github.com/logrusorgru/aurora/v3"
 	"github.com/spf13/viper"
 )
 
+var exitHooks = []func(code int){}
+
 // IsVerbose returns current verbosity configuration
 func IsVerbose() bool {
 	return viper.GetBool("verbose")
@@ -15,8 +20,12 @@ func IsVerbose() bool {
 
 // Logf tbd
 func Logf(format string, args ...interface{}) {
-	if IsVerbose() {
-		fmt.Printf(format, args...)
+	if IsVerbose() && !IsQuiet() {
+		if IsJSONLog() {
+			emit("debug", strings.TrimRight(fmt.Sprintf(format, args...), "\n"), nil)
+			return
+		}
+		fmt.Fprint(stderr, Redact(fmt.Sprintf(format, args...)))
 	}
 }
 
@@ -27,27 +36,74 @@ func Log(format string, args ...interface{}) {
 
 // Info tbd
 func Info(format string, args ...interface{}) {
-	fmt.Println(au.Sprintf(au.BrightBlue(format), args...))
+	if IsQuiet() {
+		return
+	}
+	printDiagnostic("info", au.BrightFg|au.BlueFg, format, args...)
 }
 
 // Success tbd
 func Success(format string, args ...interface{}) {
-	fmt.Println(au.Sprintf(au.BrightGreen(format), args...))
+	if IsQuiet() {
+		return
+	}
+	printDiagnostic("info", au.BrightFg|au.GreenFg, format, args...)
 }
 
 // Warning tbd
 func Warning(format string, args ...interface{}) {
-	fmt.Println(au.Sprintf(au.Yellow(format), args...))
+	if IsQuiet() {
+		return
+	}
+	if annotate("warning", Redact(fmt.Sprintf(format, args...))) {
+		return
+	}
+	printDiagnostic("warning", au.YellowFg, format, args...)
 }
 
 // Error tbd
 func Error(format string, args ...interface{}) {
-	fmt.Println(au.Sprintf(au.Red(format), args...))
+	if annotate("error", Redact(fmt.Sprintf(format, args...))) {
+		return
+	}
+	printDiagnostic("error", au.RedFg, format, args...)
 }
 
 // ErrorAndExit tbd
 func ErrorAndExit(code int, format string, args ...interface{}) int {
 	Error(format, args...)
-	os.Exit(code)
+	Exit(code)
 	return code
 }
+
+// Fail logs an error and exits with the code of its failure class
+func Fail(err error, format string, args ...interface{}) {
+	Log(err.Error())
+	ErrorAndExit(failure.ClassOf(err).Code(), format, args...)
+}
+
+// OnExit registers a function which is called with the exit code before draide exits via Exit
+func OnExit(hook func(code int)) {
+	exitHooks = append(exitHooks, hook)
+}
+
+// Exit runs the registered exit hooks and exits with the given code
+func Exit(code int) {
+	// hooks may fail themselves, so each one only runs once
+	hooks := exitHooks
+	exitHooks = nil
+	for _, hook := range hooks {
+		hook(code)
+	}
+	os.Exit(code)
+}
+
+// printDiagnostic writes a diagnostic message to stderr, either as JSON event or as text coloured if enabled
+func printDiagnostic(level string, color au.Color, format string, args ...interface{}) {
+	if IsJSONLog() {
+		emit(level, fmt.Sprintf(format, args...), nil)
+		return
+	}
+	a := colorizer()
+	fmt.Fprintln(stderr, Redact(a.Sprintf(a.Colorize(format, color), args...)))
+}